@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// xorRule is a linear rule from outside the gol package: a cell is alive when an odd number of the cells
+// two rows or two columns away from it are alive. It ignores its own state and has a radius of 2.
+type xorRule struct{}
+
+func (rule xorRule) States() int {
+	return 2
+}
+
+func (rule xorRule) Neighbourhood() []util.Cell {
+	return []util.Cell{{X: 0, Y: -2}, {X: -2, Y: 0}, {X: 2, Y: 0}, {X: 0, Y: 2}}
+}
+
+func (rule xorRule) Next(state byte, neighbours []byte) byte {
+	var next byte
+	for _, neighbour := range neighbours {
+		next ^= neighbour
+	}
+	return next
+}
+
+// TestAutomata tests the ready made Conway and HighLife rules given through Params.Automaton
+// against the images in check/images and check/rules/B36S23 for 100 turns using 1, 4 and 16 worker threads.
+func TestAutomata(t *testing.T) {
+	tests := []struct {
+		rule  gol.Rule
+		check string
+	}{
+		{gol.Conway, "check/images"},
+		{gol.HighLife, "check/rules/B36S23"},
+	}
+	for _, test := range tests {
+		for _, size := range []int{16, 64} {
+			p := gol.Params{ImageWidth: size, ImageHeight: size, Turns: 100, Automaton: test.rule}
+			expectedAlive := readAliveCells(
+				test.check+fmt.Sprintf("/%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+				p.ImageWidth,
+				p.ImageHeight,
+			)
+			for _, threads := range []int{1, 4, 16} {
+				p.Threads = threads
+				testName := fmt.Sprintf("%v-%dx%dx%d-%d", test.rule, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+				t.Run(testName, func(t *testing.T) {
+					assertEqualBoard(t, runFinal(p), expectedAlive, p)
+				})
+			}
+		}
+	}
+}
+
+// TestWireworld tests two Wireworld clocks, one sending electrons along a wire, for 0, 1, 10 and 100 turns using 1-16 worker threads.
+// The alive cells are the electron heads, and the output PGM must match check/wireworld with every state as a grey level.
+func TestWireworld(t *testing.T) {
+	p := gol.Params{ImageWidth: 40, ImageHeight: 12, Automaton: gol.Wireworld}
+	for _, turns := range []int{0, 1, 10, 100} {
+		p.Turns = turns
+		path := fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns)
+		expected, err := ioutil.ReadFile("check/wireworld/" + path)
+		util.Check(err)
+		var expectedHeads []util.Cell
+		for i, grey := range expected[len(expected)-p.ImageWidth*p.ImageHeight:] {
+			if grey == 0xFF {
+				expectedHeads = append(expectedHeads, util.Cell{X: i % p.ImageWidth, Y: i / p.ImageWidth})
+			}
+		}
+		for threads := 1; threads <= 16; threads++ {
+			p.Threads = threads
+			testName := fmt.Sprintf("%dx%dx%d-%d", p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+			t.Run(testName, func(t *testing.T) {
+				assertEqualBoard(t, runFinal(p), expectedHeads, p)
+				output, err := ioutil.ReadFile("out/" + path)
+				util.Check(err)
+				if !bytes.Equal(output, expected) {
+					t.Errorf("out/%v does not match check/wireworld/%v", path, path)
+				}
+			})
+		}
+	}
+}
+
+// TestCustomRule tests a rule defined outside the gol package, with a radius of 2, on 16x16 and 64x64 images
+// for 4 turns using 1-16 worker threads. Being linear, after 4 turns each cell is alive when an odd number
+// of the cells 8 rows or 8 columns away from it were alive in the image.
+func TestCustomRule(t *testing.T) {
+	for _, size := range []int{16, 64} {
+		p := gol.Params{ImageWidth: size, ImageHeight: size, Turns: 4, Automaton: xorRule{}}
+		initial := make(map[util.Cell]bool)
+		for _, cell := range readAliveCells(fmt.Sprintf("images/%vx%v.pgm", size, size), size, size) {
+			initial[cell] = true
+		}
+		var expectedAlive []util.Cell
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				alive := false
+				for _, offset := range (xorRule{}).Neighbourhood() {
+					neighbour := util.Cell{X: (x + 4*offset.X + size) % size, Y: (y + 4*offset.Y + size) % size}
+					alive = alive != initial[neighbour]
+				}
+				if alive {
+					expectedAlive = append(expectedAlive, util.Cell{X: x, Y: y})
+				}
+			}
+		}
+		for threads := 1; threads <= 16; threads++ {
+			p.Threads = threads
+			testName := fmt.Sprintf("%dx%dx%d-%d", p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+			t.Run(testName, func(t *testing.T) {
+				assertEqualBoard(t, runFinal(p), expectedAlive, p)
+			})
+		}
+	}
+}
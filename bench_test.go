@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"net"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"uk.ac.bris.cs/gameoflife/gol"
 )
@@ -29,6 +32,122 @@ func BenchmarkLocal(b *testing.B) {
 
 }
 
+// BenchmarkEngines compares the strip and bit engines on the same image, reporting cells computed per second.
+func BenchmarkEngines(b *testing.B) {
+	turns := 100
+	threadConfs := []int{1, 2, 4, 8, 16}
+	imageSize := 512
+	engines := []gol.Engine{gol.StripEngine, gol.BitEngine}
+
+	for _, engine := range engines {
+		for _, threads := range threadConfs {
+			p := gol.Params{
+				Turns:       turns,
+				Threads:     threads,
+				ImageWidth:  imageSize,
+				ImageHeight: imageSize,
+				Engine:      engine,
+			}
+			name := fmt.Sprintf("engine=%v_size=%dx%d_threads=%d_turns=%d_", engine, imageSize, imageSize, threads, turns)
+			b.Run(name, func(b *testing.B) {
+				start := time.Now()
+				benchmark(b, p)
+				cells := float64(b.N) * float64(p.ImageWidth*p.ImageHeight*p.Turns)
+				b.ReportMetric(cells/time.Since(start).Seconds(), "cells/s")
+			})
+		}
+	}
+}
+
+// BenchmarkLongRun reports cells computed per second on the 512x512 image as the soup settles.
+// The strip engine skips tiles that cannot change, so longer runs should compute more cells per second.
+func BenchmarkLongRun(b *testing.B) {
+	turnConfs := []int{100, 1000, 5000}
+	imageSize := 512
+
+	for _, turns := range turnConfs {
+		p := gol.Params{
+			Turns:       turns,
+			Threads:     8,
+			ImageWidth:  imageSize,
+			ImageHeight: imageSize,
+		}
+		name := fmt.Sprintf("size=%dx%d_threads=%d_turns=%d_", imageSize, imageSize, p.Threads, turns)
+		b.Run(name, func(b *testing.B) {
+			start := time.Now()
+			benchmark(b, p)
+			cells := float64(b.N) * float64(p.ImageWidth*p.ImageHeight*p.Turns)
+			b.ReportMetric(cells/time.Since(start).Seconds(), "cells/s")
+		})
+	}
+}
+
+// BenchmarkHalo compares the bytes sent to and between the workers of a broker each turn when they exchange
+// the cells beyond the edges of their strips directly, and when the broker gathers every strip.
+// Handing over the world and getting it back is measured with a run of 0 turns and taken away.
+func BenchmarkHalo(b *testing.B) {
+	turns := 100
+	workerConfs := []int{2, 4, 8}
+	imageSize := 512
+
+	for _, gather := range []bool{false, true} {
+		for _, workers := range workerConfs {
+			var sent int64
+			p := gol.Params{
+				Turns:       turns,
+				Threads:     1,
+				ImageWidth:  imageSize,
+				ImageHeight: imageSize,
+				Gather:      gather,
+				Broker: startListeningBroker(workers, func(listener net.Listener) net.Listener {
+					return countingListener{listener, &sent}
+				}),
+			}
+			name := fmt.Sprintf("gather=%v_workers=%d_size=%dx%d_turns=%d_", gather, workers, imageSize, imageSize, turns)
+			b.Run(name, func(b *testing.B) {
+				start := p
+				start.Turns = 0
+				benchmark(b, start)
+				fixed := atomic.SwapInt64(&sent, 0)
+				b.ResetTimer()
+				benchmark(b, p)
+				b.ReportMetric(float64(atomic.SwapInt64(&sent, 0)-fixed)/float64(b.N*p.Turns), "bytes/turn")
+			})
+		}
+	}
+}
+
+// countingListener adds up the bytes read and written by the connections it accepts
+type countingListener struct {
+	net.Listener
+	bytes *int64
+}
+
+func (l countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{conn, l.bytes}, nil
+}
+
+type countingConn struct {
+	net.Conn
+	bytes *int64
+}
+
+func (c countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(c.bytes, int64(n))
+	return n, err
+}
+
+func (c countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(c.bytes, int64(n))
+	return n, err
+}
+
 func benchmark(b *testing.B, p gol.Params) {
 	for i := 0; i < b.N; i++ {
 		events := make(chan gol.Event)
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestBoundaries sends four gliders across each edge of a 64x48 image for 16 and 32 turns using 1-16 worker threads,
+// once for every boundary topology.
+func TestBoundaries(t *testing.T) {
+	boundaries := map[string]gol.Params{
+		"torus":   {Boundary: gol.Torus},
+		"dead":    {Boundary: gol.DeadEdges},
+		"klein":   {Boundary: gol.KleinBottle},
+		"cross":   {Boundary: gol.CrossSurface},
+		"twisted": {Boundary: gol.TwistedTorus, Shift: 7},
+	}
+	for name, p := range boundaries {
+		p.ImageWidth = 64
+		p.ImageHeight = 48
+		for _, turns := range []int{16, 32} {
+			p.Turns = turns
+			expectedAlive := readAliveCells(
+				"check/boundaries/"+name+fmt.Sprintf("/%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+				p.ImageWidth,
+				p.ImageHeight,
+			)
+			for threads := 1; threads <= 16; threads++ {
+				p.Threads = threads
+				testName := fmt.Sprintf("%s-%dx%dx%d-%d", name, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+				t.Run(testName, func(t *testing.T) {
+					events := make(chan gol.Event)
+					go gol.Run(p, events, nil)
+					var cells []util.Cell
+					for event := range events {
+						switch e := event.(type) {
+						case gol.FinalTurnComplete:
+							cells = e.Alive
+						}
+					}
+					assertEqualBoard(t, cells, expectedAlive, p)
+				})
+			}
+		}
+	}
+}
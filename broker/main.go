@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// main starts a broker with 'go run ./broker', which workers register with and controllers hand their worlds to
+func main() {
+	address := flag.String(
+		"addr",
+		"127.0.0.1:8030",
+		"Specify the address to listen on for workers and controllers. Defaults to 127.0.0.1:8030.")
+
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", *address)
+	util.Check(err)
+	fmt.Println("Broker:", listener.Addr())
+	gol.ServeBroker(listener)
+}
@@ -117,7 +117,7 @@ func (c *BoolChannel) Receive(block bool) (bool, success bool) {
 	}()
 	value := *c.value
 	c.value = nil
-	return value, false
+	return value, true
 }
 
 
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestColour tests reading and writing P6 PPM images with Wireworld's palette, with black and white for Life
+// and with the colours of Immigration and QuadLife, using 1-16 worker threads.
+// The output PPM must match check/colour, and replaying the CellsChanged events must give the final state of every cell.
+func TestColour(t *testing.T) {
+	tests := []struct {
+		p     gol.Params
+		check string
+		turns []int
+	}{
+		{gol.Params{ImageWidth: 40, ImageHeight: 12, Rule: "Wireworld"}, "check/colour/wireworld", []int{0, 1, 10, 100}},
+		{gol.Params{ImageWidth: 16, ImageHeight: 16}, "check/colour/life", []int{0, 1, 100}},
+		{gol.Params{ImageWidth: 32, ImageHeight: 32, Rule: "Immigration"}, "check/colour/immigration", []int{0, 1, 100}},
+		{gol.Params{ImageWidth: 48, ImageHeight: 48, Rule: "QuadLife"}, "check/colour/quadlife", []int{0, 1, 100}},
+	}
+	for _, test := range tests {
+		p := test.p
+		p.Colour = true
+		colours, err := gol.StateColours(p)
+		util.Check(err)
+		for _, turns := range test.turns {
+			p.Turns = turns
+			path := fmt.Sprintf("%vx%vx%v.ppm", p.ImageWidth, p.ImageHeight, p.Turns)
+			expected, err := ioutil.ReadFile(test.check + "/" + path)
+			util.Check(err)
+			expectedStates := createStates(p)
+			pixels := expected[len(expected)-3*p.ImageWidth*p.ImageHeight:]
+			for i := 0; i < len(pixels); i += 3 {
+				for state, colour := range colours {
+					if colour.R == pixels[i] && colour.G == pixels[i+1] && colour.B == pixels[i+2] {
+						expectedStates[i/3/p.ImageWidth][i/3%p.ImageWidth] = byte(state)
+					}
+				}
+			}
+
+			for _, threads := range []int{1, 2, 3, 8, 16} {
+				p.Threads = threads
+				testName := fmt.Sprintf("%v-%dx%dx%d-%d", p.Rule, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+				t.Run(testName, func(t *testing.T) {
+					events := make(chan gol.Event)
+					go gol.Run(p, events, nil)
+					changed := createStates(p)
+					var final gol.FinalTurnComplete
+					for event := range events {
+						switch e := event.(type) {
+						case gol.CellFlipped, gol.CellsFlipped:
+							t.Fatalf("%T event sent with Colour set", e)
+						case gol.CellsChanged:
+							for k, cell := range e.Cells {
+								changed[cell.Y][cell.X] = e.States[k]
+							}
+						case gol.FinalTurnComplete:
+							final = e
+						}
+					}
+					for y, row := range expectedStates {
+						if !bytes.Equal(final.States[y], row) {
+							t.Fatalf("row %d has states %v, should be %v", y, final.States[y], row)
+						}
+						if !bytes.Equal(changed[y], row) {
+							t.Fatalf("CellsChanged events leave row %d with states %v, should be %v", y, changed[y], row)
+						}
+					}
+					output, err := ioutil.ReadFile("out/" + path)
+					util.Check(err)
+					if !bytes.Equal(output, expected) {
+						t.Errorf("out/%v does not match %v/%v", path, test.check, path)
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestNamedRules tests giving the ready made rules by name.
+func TestNamedRules(t *testing.T) {
+	named := map[string]gol.Rule{
+		"Wireworld":     gol.Wireworld,
+		"wire world":    gol.Wireworld,
+		"Brian's Brain": gol.BriansBrain,
+		"StarWars":      gol.StarWars,
+		"HighLife":      gol.HighLife,
+		"Bosco":         gol.Bosco,
+		"Immigration":   gol.Immigration,
+		"Quad Life":     gol.QuadLife,
+	}
+	for name, expected := range named {
+		rule, err := gol.ParseRulestring(name)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", name, err)
+		} else if rule != expected {
+			t.Errorf("%q parsed as %v, should be %v", name, rule, expected)
+		}
+	}
+	if _, err := gol.ParseRulestring("Wireworlds"); err == nil {
+		t.Errorf("%q: expected an error", "Wireworlds")
+	}
+}
+
+// make a grid of states 0 the size of the image
+func createStates(p gol.Params) [][]byte {
+	states := make([][]byte, p.ImageHeight)
+	for i := range states {
+		states[i] = make([]byte, p.ImageWidth)
+	}
+	return states
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestDistributed runs TestGol on a broker with 3 workers, all listening on localhost ports.
+func TestDistributed(t *testing.T) {
+	testGol(t, startBroker(3))
+}
+
+// TestDistributedRules tests the boundaries, a larger neighbourhood, more states, a hexagonal rule and random births
+// on a broker with 1 and 5 workers using 1 and 4 threads each, with the workers exchanging halos directly
+// and with the broker gathering every strip. The output PGM must match the images in check.
+func TestDistributedRules(t *testing.T) {
+	tests := []struct {
+		p     gol.Params
+		check string
+	}{
+		{gol.Params{ImageWidth: 64, ImageHeight: 48, Turns: 32, Boundary: gol.DeadEdges}, "check/boundaries/dead"},
+		{gol.Params{ImageWidth: 64, ImageHeight: 48, Turns: 32, Boundary: gol.KleinBottle}, "check/boundaries/klein"},
+		{gol.Params{ImageWidth: 64, ImageHeight: 48, Turns: 32, Boundary: gol.CrossSurface}, "check/boundaries/cross"},
+		{gol.Params{ImageWidth: 64, ImageHeight: 48, Turns: 32, Boundary: gol.TwistedTorus, Shift: 7}, "check/boundaries/twisted"},
+		{gol.Params{ImageWidth: 256, ImageHeight: 256, Turns: 100, Rule: "R5,C0,M1,S34..58,B34..45,NM"}, "check/ltl/bosco"},
+		{gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Rule: "B2/S/C3"}, "check/generations/B2SC3"},
+		{gol.Params{ImageWidth: 15, ImageHeight: 17, Turns: 100, Rule: "B2/S34H"}, "check/hex/B2S34H"},
+		{gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Stochastic: true, BirthProbability: 0.95, DeathProbability: 0.01, Seed: 42}, "check/stochastic"},
+	}
+	for _, workers := range []int{1, 5} {
+		broker := startBroker(workers)
+		for _, test := range tests {
+			p := test.p
+			p.Broker = broker
+			path := fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns)
+			expected, err := ioutil.ReadFile(test.check + "/" + path)
+			util.Check(err)
+			for _, gather := range []bool{false, true} {
+				for _, threads := range []int{1, 4} {
+					p.Threads, p.Gather = threads, gather
+					testName := fmt.Sprintf("%s-%dx%dx%d-%d-%d-gather=%v", test.check, p.ImageWidth, p.ImageHeight, p.Turns, workers, p.Threads, p.Gather)
+					t.Run(testName, func(t *testing.T) {
+						runFinal(p)
+						output, err := ioutil.ReadFile("out/" + path)
+						util.Check(err)
+						if !bytes.Equal(output, expected) {
+							t.Errorf("out/%v does not match %v/%v", path, test.check, path)
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+// TestDistributedFlips tests that the cells flipped on a broker are sent back as events that rebuild the final board,
+// and that a controller which stalls gets every turn it missed merged into the next frame instead of holding up
+// the broker, while still getting a TurnComplete event for each of them.
+func TestDistributedFlips(t *testing.T) {
+	broker := startBroker(3)
+	client, err := rpc.Dial("tcp", broker)
+	util.Check(err)
+	defer client.Close()
+	for _, batch := range []bool{false, true} {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 2, Broker: broker, BatchFlips: batch}
+		testName := fmt.Sprintf("%dx%dx%d-batch=%v", p.ImageWidth, p.ImageHeight, p.Turns, p.BatchFlips)
+		t.Run(testName, func(t *testing.T) {
+			// the broker is held after its first turn, then the controller stalls until the broker has been paused
+			// after turn 20
+			pause := client.Go("Broker.Pause", gol.PauseRequest{Paused: true, Turn: 1}, new(gol.Empty), nil)
+			events := make(chan gol.Event)
+			go gol.Run(p, events, nil)
+			var cells []util.Cell
+			flipped := make(map[util.Cell]bool)
+			turns := []int{}
+			// the controller fetches at most one more frame before it stalls, then the rest up to turn 20 are merged
+			early := map[int]bool{}
+			flip := func(cell util.Cell, turn int) {
+				flipped[cell] = !flipped[cell]
+				if len(turns) > 0 && turn < 19 {
+					early[turn] = true
+				}
+			}
+			for event := range events {
+				switch e := event.(type) {
+				case gol.CellFlipped:
+					flip(e.Cell, e.CompletedTurns)
+				case gol.CellsFlipped:
+					for _, cell := range e.Cells {
+						flip(cell, e.CompletedTurns)
+					}
+				case gol.TurnComplete:
+					turns = append(turns, e.CompletedTurns)
+					if len(turns) == 1 {
+						util.Check((<-pause.Done).Error)
+						util.Check(client.Call("Broker.Pause", gol.PauseRequest{Paused: false}, new(gol.Empty)))
+						util.Check(client.Call("Broker.Pause", gol.PauseRequest{Paused: true, Turn: 20}, new(gol.Empty)))
+						util.Check(client.Call("Broker.Pause", gol.PauseRequest{Paused: false}, new(gol.Empty)))
+					}
+				case gol.FinalTurnComplete:
+					cells = e.Alive
+				}
+			}
+			if len(early) > 1 {
+				t.Errorf("cells flipped after stalling in turns %v, the turns up to 19 should be merged", early)
+			}
+			for k, turn := range turns {
+				if turn != k {
+					t.Fatalf("TurnComplete events for turns %v, should be one for every turn", turns)
+				}
+			}
+			if len(turns) != p.Turns {
+				t.Errorf("%d TurnComplete events, should be %d", len(turns), p.Turns)
+			}
+			var flippedAlive []util.Cell
+			for cell, alive := range flipped {
+				if alive {
+					flippedAlive = append(flippedAlive, cell)
+				}
+			}
+			expectedAlive := readAliveCells(
+				"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+				p.ImageWidth,
+				p.ImageHeight,
+			)
+			assertEqualBoard(t, cells, expectedAlive, p)
+			assertEqualBoard(t, flippedAlive, expectedAlive, p)
+		})
+	}
+}
+
+// TestDistributedDetach tests that a controller can pause a run and leave it on the broker with 'q',
+// that the broker carries on with the run once resumed without a controller, and that another controller
+// attaching to it gets the world where the broker was paused and finishes the run with the final board in
+// check/images. Pressing 'k' must write the final image and shut the broker down.
+func TestDistributedDetach(t *testing.T) {
+	p := gol.Params{ImageWidth: 512, ImageHeight: 512, Turns: 100, Threads: 2, Broker: startBroker(3)}
+	client, err := rpc.Dial("tcp", p.Broker)
+	util.Check(err)
+	defer client.Close()
+
+	// the first controller pauses after its first turn, then leaves
+	left := -1
+	for event := range runKeys(p, map[gol.State]rune{gol.Paused: 'q'}, 'p') {
+		if e, ok := event.(gol.TurnComplete); ok {
+			left = e.CompletedTurns
+		}
+	}
+	// the broker carries on without it until turn 50
+	util.Check(client.Call("Broker.Pause", gol.PauseRequest{Paused: false}, new(gol.Empty)))
+	util.Check(client.Call("Broker.Pause", gol.PauseRequest{Paused: true, Turn: 50}, new(gol.Empty)))
+
+	attached := p
+	attached.Attach, attached.Turns = true, 0
+	var cells []util.Cell
+	turns := []int{}
+	flipped := make(map[util.Cell]bool)
+	resumed := -1
+	for event := range runKeys(attached, map[gol.State]rune{gol.Paused: 'p'}, 0) {
+		switch e := event.(type) {
+		case gol.StateChange:
+			if resumed < 0 {
+				resumed = e.CompletedTurns
+				if e.NewState != gol.Paused || e.CompletedTurns < 50 || e.CompletedTurns <= left {
+					t.Errorf("attached in state %v after turn %d, the broker was paused after turn 50 or later",
+						e.NewState, e.CompletedTurns)
+				}
+			}
+		case gol.CellFlipped:
+			flipped[e.Cell] = !flipped[e.Cell]
+		case gol.TurnComplete:
+			turns = append(turns, e.CompletedTurns)
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+	for k, turn := range turns {
+		if turn != resumed+k {
+			t.Fatalf("TurnComplete events for turns %v after attaching, should be one for each turn from %d", turns, resumed)
+		}
+	}
+	var flippedAlive []util.Cell
+	for cell, alive := range flipped {
+		if alive {
+			flippedAlive = append(flippedAlive, cell)
+		}
+	}
+	expectedAlive := readAliveCells(
+		"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+		p.ImageWidth,
+		p.ImageHeight,
+	)
+	assertEqualBoard(t, cells, expectedAlive, p)
+	assertEqualBoard(t, flippedAlive, expectedAlive, p)
+
+	// 'k' writes the final image, then shuts down the broker
+	p.Broker = startBroker(3)
+	started := time.Now()
+	quit := -1
+	for event := range runKeys(p, map[gol.State]rune{gol.Paused: 'k'}, 'p') {
+		if e, ok := event.(gol.StateChange); ok && e.NewState == gol.Quitting {
+			quit = e.CompletedTurns
+		}
+	}
+	path := fmt.Sprintf("out/%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, quit)
+	if info, err := os.Stat(path); err != nil || info.ModTime().Before(started) {
+		t.Errorf("%v not written before shutting down", path)
+	}
+	if conn, err := net.Dial("tcp", p.Broker); err == nil {
+		conn.Close()
+		t.Error("the broker is still listening after pressing 'k'")
+	}
+}
+
+// run the params, pressing first after the first turn and a key whenever the state changes to one in keys.
+// Returns the events, which have to be read until the channel is closed
+func runKeys(p gol.Params, keys map[gol.State]rune, first rune) <-chan gol.Event {
+	events := make(chan gol.Event)
+	forwarded := make(chan gol.Event)
+	keyPresses := make(chan rune, 10)
+	go gol.Run(p, events, keyPresses)
+	go func() {
+		for event := range events {
+			switch e := event.(type) {
+			case gol.TurnComplete:
+				if first != 0 {
+					keyPresses <- first
+					first = 0
+				}
+			case gol.StateChange:
+				if key, ok := keys[e.NewState]; ok {
+					keyPresses <- key
+					delete(keys, e.NewState)
+				}
+			}
+			forwarded <- event
+		}
+		close(forwarded)
+	}()
+	return forwarded
+}
+
+// start a broker and workers registered with it on free localhost ports, returning the address of the broker
+func startBroker(workers int) string {
+	return startListeningBroker(workers, func(listener net.Listener) net.Listener { return listener })
+}
+
+// start a broker and workers on localhost ports, wrapping the listener of every worker
+func startListeningBroker(workers int, wrap func(net.Listener) net.Listener) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	util.Check(err)
+	go gol.ServeBroker(listener)
+	for i := 0; i < workers; i++ {
+		worker, err := net.Listen("tcp", "127.0.0.1:0")
+		util.Check(err)
+		util.Check(gol.ServeWorker(wrap(worker), listener.Addr().String()))
+	}
+	return listener.Addr().String()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestElementary tests rules 30 and 110 from the first row of an image, using 1-16 worker threads.
+// The output PGM is the spacetime diagram, with a row for every generation, and must match check/elementary.
+func TestElementary(t *testing.T) {
+	tests := []struct {
+		rule  string
+		size  util.Cell
+		turns []int
+	}{
+		{"W30", util.Cell{X: 64, Y: 64}, []int{0, 1, 100}},
+		{"W110", util.Cell{X: 64, Y: 64}, []int{100}},
+		{"W110", util.Cell{X: 1000, Y: 600}, []int{1, 200}},
+	}
+	for _, test := range tests {
+		for _, turns := range test.turns {
+			p := gol.Params{ImageWidth: test.size.X, ImageHeight: test.size.Y, Turns: turns, Rule: test.rule}
+			path := fmt.Sprintf("%v/%vx%vx%v.pgm", test.rule, p.ImageWidth, p.ImageHeight, p.Turns)
+			expected, err := ioutil.ReadFile("check/elementary/" + path)
+			util.Check(err)
+			expectedAlive := readAliveCells("check/elementary/"+path, p.ImageWidth, p.Turns+1)
+			for threads := 1; threads <= 16; threads++ {
+				p.Threads = threads
+				testName := fmt.Sprintf("%v-%dx%dx%d-%d", test.rule, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+				t.Run(testName, func(t *testing.T) {
+					assertEqualBoard(t, runFinal(p), expectedAlive, p)
+					output, err := ioutil.ReadFile(fmt.Sprintf("out/%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns))
+					util.Check(err)
+					if !bytes.Equal(output, expected) {
+						t.Errorf("output does not match check/elementary/%v", path)
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestSierpinski tests rule 90 from a single cell, which draws Pascal's triangle mod 2:
+// generation t has a cell k steps right of the leftmost one it can reach alive when t choose k/2 is odd.
+func TestSierpinski(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 31, Rule: "W90", SingleCell: true}
+	var expectedAlive []util.Cell
+	for turn := 0; turn <= p.Turns; turn++ {
+		for k := 0; k <= turn; k++ {
+			// Lucas' theorem: t choose k is odd when the bits of k are a subset of the bits of t
+			if k&turn == k {
+				expectedAlive = append(expectedAlive, util.Cell{X: p.ImageWidth/2 - turn + 2*k, Y: turn})
+			}
+		}
+	}
+	for threads := 1; threads <= 16; threads++ {
+		p.Threads = threads
+		testName := fmt.Sprintf("%dx%dx%d-%d", p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+		t.Run(testName, func(t *testing.T) {
+			assertEqualBoard(t, runFinal(p), expectedAlive, p)
+		})
+	}
+}
+
+// TestParseElementary tests parsing Wolfram rule numbers.
+func TestParseElementary(t *testing.T) {
+	valid := map[string]string{
+		"W30":  "W30",
+		"w110": "W110",
+		"W0":   "W0",
+		"W255": "W255",
+	}
+	for rulestring, expected := range valid {
+		rule, err := gol.ParseRulestring(rulestring)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", rulestring, err)
+		} else if fmt.Sprint(rule) != expected {
+			t.Errorf("%q parsed as %v, should be %v", rulestring, rule, expected)
+		}
+	}
+	for _, rulestring := range []string{"W256", "W-1", "W", "W3x"} {
+		if _, err := gol.ParseRulestring(rulestring); err == nil {
+			t.Errorf("%q: expected an error", rulestring)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestBitEngine tests the bit-packed engine on the TestGol images, a non-Conway rule and every boundary using 1-16 worker threads.
+// The CellFlipped events must also rebuild the final board, as they do for the strip engine.
+func TestBitEngine(t *testing.T) {
+	type bitTest struct {
+		p     gol.Params
+		check string
+	}
+	var tests []bitTest
+	for _, p := range []gol.Params{
+		{ImageWidth: 16, ImageHeight: 16},
+		{ImageWidth: 64, ImageHeight: 64},
+		{ImageWidth: 512, ImageHeight: 512},
+		{ImageWidth: 15, ImageHeight: 17},
+	} {
+		for _, turns := range []int{0, 1, 100} {
+			p.Turns = turns
+			tests = append(tests, bitTest{p, "check/images"})
+		}
+	}
+	tests = append(tests, bitTest{gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Rule: "B36/S23"}, "check/rules/B36S23"})
+	boundaries := map[string]gol.Params{
+		"dead":    {Boundary: gol.DeadEdges},
+		"klein":   {Boundary: gol.KleinBottle},
+		"cross":   {Boundary: gol.CrossSurface},
+		"twisted": {Boundary: gol.TwistedTorus, Shift: 7},
+	}
+	for name, p := range boundaries {
+		p.ImageWidth, p.ImageHeight, p.Turns = 64, 48, 32
+		tests = append(tests, bitTest{p, "check/boundaries/" + name})
+	}
+
+	for _, test := range tests {
+		p := test.p
+		p.Engine = gol.BitEngine
+		expectedAlive := readAliveCells(
+			test.check+fmt.Sprintf("/%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+			p.ImageWidth,
+			p.ImageHeight,
+		)
+		for threads := 1; threads <= 16; threads++ {
+			p.Threads = threads
+			testName := fmt.Sprintf("%s-%dx%dx%d-%d", test.check, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+			t.Run(testName, func(t *testing.T) {
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				var cells []util.Cell
+				flipped := make(map[util.Cell]bool)
+				for event := range events {
+					switch e := event.(type) {
+					case gol.CellFlipped:
+						flipped[e.Cell] = !flipped[e.Cell]
+					case gol.FinalTurnComplete:
+						cells = e.Alive
+					}
+				}
+				assertEqualBoard(t, cells, expectedAlive, p)
+				var flippedAlive []util.Cell
+				for cell, alive := range flipped {
+					if alive {
+						flippedAlive = append(flippedAlive, cell)
+					}
+				}
+				assertEqualBoard(t, flippedAlive, expectedAlive, p)
+			})
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/rpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestFaultTolerance runs the TestGol images for 100 turns on a broker with 4 worker processes, killing one
+// after 3 random turns of the broker and sometimes starting a new one to take its place. The broker must roll back to its last
+// checkpoint every time, so the final board still matches check/images.
+func TestFaultTolerance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+	binary := filepath.Join(dir, "worker")
+	build := exec.Command("go", "build", "-o", binary, "uk.ac.bris.cs/gameoflife/worker")
+	build.Stderr = os.Stderr
+	util.Check(build.Run())
+
+	seed := time.Now().UnixNano()
+	t.Logf("killing workers with seed %d", seed)
+	random := rand.New(rand.NewSource(seed))
+
+	tests := []gol.Params{
+		{ImageWidth: 16, ImageHeight: 16},
+		{ImageWidth: 64, ImageHeight: 64},
+		{ImageWidth: 512, ImageHeight: 512},
+		{ImageWidth: 15, ImageHeight: 17},
+	}
+	for _, p := range tests {
+		for _, checkpoint := range []int{1, 10, 100} {
+			p.Turns, p.Threads, p.CheckpointTurns = 100, 2, checkpoint
+			kills := map[int]bool{}
+			for len(kills) < 3 {
+				kills[1+random.Intn(p.Turns-1)] = true
+			}
+			var killTurns []int
+			for turn := range kills {
+				killTurns = append(killTurns, turn)
+			}
+			sort.Ints(killTurns)
+			testName := fmt.Sprintf("%dx%dx%d-checkpoint=%d", p.ImageWidth, p.ImageHeight, p.Turns, p.CheckpointTurns)
+			t.Run(testName, func(t *testing.T) {
+				listener, err := net.Listen("tcp", "127.0.0.1:0")
+				util.Check(err)
+				go gol.ServeBroker(listener)
+				p.Broker = listener.Addr().String()
+				var workers []*exec.Cmd
+				defer func() {
+					for _, worker := range workers {
+						worker.Process.Kill()
+						worker.Wait()
+					}
+				}()
+				for i := 0; i < 4; i++ {
+					workers = append(workers, startWorkerProcess(binary, p.Broker))
+				}
+
+				// the broker runs ahead of the events, so it is paused at each turn while a worker is killed
+				client, err := rpc.Dial("tcp", p.Broker)
+				util.Check(err)
+				defer client.Close()
+				killed := make(chan bool)
+				go func() {
+					defer close(killed)
+					for _, turn := range killTurns {
+						if err := client.Call("Broker.Pause", gol.PauseRequest{Paused: true, Turn: turn}, new(gol.Empty)); err != nil {
+							t.Errorf("could not pause the broker after turn %d: %v", turn, err)
+							return
+						}
+						// always leave a worker to carry on with
+						if len(workers) == 1 || random.Intn(2) == 0 {
+							workers = append(workers, startWorkerProcess(binary, p.Broker))
+						}
+						k := random.Intn(len(workers))
+						t.Logf("killing worker %d of %d after turn %d", k, len(workers), turn)
+						util.Check(workers[k].Process.Kill())
+						workers[k].Wait()
+						workers = append(workers[:k], workers[k+1:]...)
+						util.Check(client.Call("Broker.Pause", gol.PauseRequest{Paused: false}, new(gol.Empty)))
+					}
+				}()
+
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				var cells []util.Cell
+				for event := range events {
+					switch e := event.(type) {
+					case gol.FinalTurnComplete:
+						cells = e.Alive
+					}
+				}
+				<-killed
+				expectedAlive := readAliveCells(
+					"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+					p.ImageWidth,
+					p.ImageHeight,
+				)
+				assertEqualBoard(t, cells, expectedAlive, p)
+			})
+		}
+	}
+}
+
+// start a worker process on a free localhost port, waiting for it to register with the broker
+func startWorkerProcess(binary, broker string) *exec.Cmd {
+	worker := exec.Command(binary, "-addr", "127.0.0.1:0", "-broker", broker)
+	worker.Stderr = os.Stderr
+	stdout, err := worker.StdoutPipe()
+	util.Check(err)
+	util.Check(worker.Start())
+	_, err = bufio.NewReader(stdout).ReadString('\n')
+	util.Check(err)
+	return worker
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestBatchFlips tests that every engine sends CellsFlipped events instead of CellFlipped events when BatchFlips is set,
+// at most one per worker each turn and all before the TurnComplete event, and that they rebuild the final board.
+func TestBatchFlips(t *testing.T) {
+	engines := []gol.Engine{gol.StripEngine, gol.BitEngine, gol.HashLifeEngine, gol.SparseEngine}
+	for _, engine := range engines {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Engine: engine, BatchFlips: true}
+		for _, threads := range []int{1, 4, 16} {
+			p.Threads = threads
+			testName := fmt.Sprintf("%v-%dx%dx%d-%d", p.Engine, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+			t.Run(testName, func(t *testing.T) {
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				var cells []util.Cell
+				flipped := make(map[util.Cell]bool)
+				// the cells alive in the image are sent in one more batch before the first turn
+				batches := -1
+				for event := range events {
+					switch e := event.(type) {
+					case gol.CellFlipped:
+						t.Fatalf("CellFlipped event sent for %v with BatchFlips set", e.Cell)
+					case gol.CellsFlipped:
+						batches++
+						if batches > p.Threads {
+							t.Fatalf("%d CellsFlipped events sent for turn %d, should be at most %d", batches, e.CompletedTurns, p.Threads)
+						}
+						for _, cell := range e.Cells {
+							flipped[cell] = !flipped[cell]
+						}
+					case gol.TurnComplete:
+						batches = 0
+					case gol.FinalTurnComplete:
+						cells = e.Alive
+					}
+				}
+				var flippedAlive []util.Cell
+				for cell, alive := range flipped {
+					if alive {
+						flippedAlive = append(flippedAlive, cell)
+					}
+				}
+				if len(flippedAlive) != len(cells) {
+					t.Fatalf("CellsFlipped events leave %d alive cells, should be %d", len(flippedAlive), len(cells))
+				}
+				for _, cell := range cells {
+					if !flipped[cell] {
+						t.Fatalf("cell %v is alive but was not flipped by CellsFlipped events", cell)
+					}
+				}
+			})
+		}
+	}
+}
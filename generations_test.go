@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestGenerations tests Brian's Brain and Star Wars on the 64x64 image for 1, 10 and 100 turns, and Star Wars on a 48x48 image
+// that starts with dying cells, using 1-16 worker threads. The output PGM, the alive cells and the state of every cell
+// must match the grey levels in check/generations.
+func TestGenerations(t *testing.T) {
+	type generationsTest struct {
+		p     gol.Params
+		check string
+	}
+	var tests []generationsTest
+	for _, rule := range []string{"B2/S/C3", "B2/S345/C4"} {
+		for _, turns := range []int{1, 10, 100} {
+			p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: turns, Rule: rule}
+			tests = append(tests, generationsTest{p, "check/generations/" + strings.ReplaceAll(rule, "/", "")})
+		}
+	}
+	for _, turns := range []int{0, 1, 10, 100} {
+		p := gol.Params{ImageWidth: 48, ImageHeight: 48, Turns: turns, Automaton: gol.StarWars}
+		tests = append(tests, generationsTest{p, "check/generations/B2S345C4"})
+	}
+
+	for _, test := range tests {
+		p := test.p
+		rule, err := gol.ParseRulestring(p.Rule)
+		util.Check(err)
+		if p.Automaton != nil {
+			rule = p.Automaton
+		}
+		path := fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns)
+		expected, err := ioutil.ReadFile(test.check + "/" + path)
+		util.Check(err)
+		expectedStates := greysToStates(expected[len(expected)-p.ImageWidth*p.ImageHeight:], rule.States(), p.ImageWidth)
+		var expectedAlive []util.Cell
+		for y, row := range expectedStates {
+			for x, state := range row {
+				if state == 1 {
+					expectedAlive = append(expectedAlive, util.Cell{X: x, Y: y})
+				}
+			}
+		}
+
+		for threads := 1; threads <= 16; threads++ {
+			p.Threads = threads
+			testName := fmt.Sprintf("%v-%dx%dx%d-%d", rule, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+			t.Run(testName, func(t *testing.T) {
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				var final gol.FinalTurnComplete
+				for event := range events {
+					if e, ok := event.(gol.FinalTurnComplete); ok {
+						final = e
+					}
+				}
+				assertEqualBoard(t, final.Alive, expectedAlive, p)
+				for y, row := range expectedStates {
+					if !bytes.Equal(final.States[y], row) {
+						t.Fatalf("row %d has states %v, should be %v", y, final.States[y], row)
+					}
+				}
+				output, err := ioutil.ReadFile("out/" + path)
+				util.Check(err)
+				if !bytes.Equal(output, expected) {
+					t.Errorf("out/%v does not match %v/%v", path, test.check, path)
+				}
+			})
+		}
+	}
+}
+
+// TestParseGenerations tests parsing B/S/C and S/B/C rulestrings, and rejecting bad numbers of states.
+func TestParseGenerations(t *testing.T) {
+	valid := map[string]string{
+		"B2/S/C3":    "B2/S/C3",
+		"/2/3":       "B2/S/C3",
+		"345/2/4":    "B2/S345/C4",
+		"b2/s345/c4": "B2/S345/C4",
+		"B3/S23/2":   "B3/S23/C2",
+	}
+	for rulestring, expected := range valid {
+		rule, err := gol.ParseRulestring(rulestring)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", rulestring, err)
+		} else if fmt.Sprint(rule) != expected {
+			t.Errorf("%q parsed as %v, should be %v", rulestring, rule, expected)
+		}
+	}
+	for _, rulestring := range []string{"B2/S/C1", "B2/S/C257", "B2/S/Cx", "B2/S/", "B9/S/C3"} {
+		if _, err := gol.ParseRulestring(rulestring); err == nil {
+			t.Errorf("%q: expected an error", rulestring)
+		}
+	}
+}
+
+// get the state of each cell from its grey level, white being state 1 and each state after getting darker
+func greysToStates(greys []byte, states, width int) [][]byte {
+	lookup := make(map[byte]byte)
+	for state := 1; state < states; state++ {
+		lookup[byte(255-(state-1)*255/(states-1))] = byte(state)
+	}
+	var rows [][]byte
+	for len(greys) > 0 {
+		row := make([]byte, width)
+		for x, grey := range greys[:width] {
+			row[x] = lookup[grey]
+		}
+		rows = append(rows, row)
+		greys = greys[width:]
+	}
+	return rows
+}
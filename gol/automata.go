@@ -0,0 +1,202 @@
+package gol
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"unicode"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Rule is a cellular automaton, giving the next state of each cell from its own state and the states of its neighbourhood.
+// States are numbered from 0, which is dead, and state 1 counts as alive. In images each state is a grey level:
+// black for state 0, white for state 1 and getting darker for every state after that.
+// Rules from outside this package are used by setting Params.Automaton.
+type Rule interface {
+	// States gets the number of states a cell can be in, at most 256
+	States() int
+	// Neighbourhood gets the positions of the neighbours of a cell relative to it, in the order they are passed to Next
+	Neighbourhood() []util.Cell
+	// Next gets the next state of a cell from its state and the states of its neighbours
+	Next(state byte, neighbours []byte) byte
+}
+
+// ready made rules for Params.Automaton
+var (
+	Conway    = LifeRule{Birth: [9]bool{3: true}, Survival: [9]bool{2: true, 3: true}}
+	HighLife  = LifeRule{Birth: [9]bool{3: true, 6: true}, Survival: [9]bool{2: true, 3: true}}
+	Wireworld = WireworldRule{}
+
+	BriansBrain = GenerationsRule{LifeRule: LifeRule{Birth: [9]bool{2: true}}, Generations: 3}
+	StarWars    = GenerationsRule{LifeRule: LifeRule{Birth: [9]bool{2: true}, Survival: [9]bool{3: true, 4: true, 5: true}}, Generations: 4}
+
+	Bosco = LargerThanLifeRule{Radius: 5, Shape: Moore, Middle: true, Generations: 2, BirthMin: 34, BirthMax: 45, SurvivalMin: 34, SurvivalMax: 58}
+)
+
+// the ready made rules that can be given by name as a rulestring, in lower case
+var namedRules = map[string]Rule{
+	"life":        Conway,
+	"highlife":    HighLife,
+	"wireworld":   Wireworld,
+	"briansbrain": BriansBrain,
+	"starwars":    StarWars,
+	"bosco":       Bosco,
+	"immigration": Immigration,
+	"quadlife":    QuadLife,
+}
+
+// get a ready made rule from its name, ignoring case and anything other than letters
+func namedRule(name string) (Rule, bool) {
+	letters := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+	rule, ok := namedRules[letters]
+	return rule, ok
+}
+
+// PaletteRule is a Rule with its own colour for each state, used instead of grey levels when Params.Colour is set
+type PaletteRule interface {
+	Rule
+	// Palette gets the colour of each state
+	Palette() []color.RGBA
+}
+
+// StateColours gets the colour of each state of the rule selected by the params,
+// from its palette if it has one, otherwise the grey levels used in PGM images
+func StateColours(p Params) ([]color.RGBA, error) {
+	rule, err := selectRule(p)
+	if err != nil {
+		return nil, err
+	}
+	if palette, ok := rule.(PaletteRule); ok {
+		colours := palette.Palette()
+		if len(colours) != rule.States() {
+			return nil, fmt.Errorf("rule %v has %d states but %d colours", rule, rule.States(), len(colours))
+		}
+		return colours, nil
+	}
+	var colours []color.RGBA
+	for _, grey := range stateGreys(rule.States()) {
+		colours = append(colours, color.RGBA{R: grey, G: grey, B: grey, A: 0xFF})
+	}
+	return colours, nil
+}
+
+// get the rule selected by the params, Params.Automaton if it is set, otherwise the Params.Rule rulestring
+func selectRule(p Params) (Rule, error) {
+	if p.Automaton == nil {
+		return ParseRulestring(p.Rule)
+	}
+	if states := p.Automaton.States(); states < 2 || states > 256 {
+		return nil, fmt.Errorf("rule %v has %d states, must have 2-256", p.Automaton, states)
+	}
+	return p.Automaton, nil
+}
+
+// MooreNeighbourhood gets the cells at most radius rows and columns away, row by row from the top left
+func MooreNeighbourhood(radius int) []util.Cell {
+	return Moore.Neighbourhood(radius)
+}
+
+// get the furthest number of rows or columns away any neighbour is
+func neighbourhoodRadius(neighbourhood []util.Cell) int {
+	radius := 0
+	for _, offset := range neighbourhood {
+		for _, d := range []int{offset.X, -offset.X, offset.Y, -offset.Y} {
+			if d > radius {
+				radius = d
+			}
+		}
+	}
+	return radius
+}
+
+// get the grey level used for each state in images
+func stateGreys(states int) []byte {
+	greys := make([]byte, states)
+	for state := 1; state < states; state++ {
+		greys[state] = byte(255 - (state-1)*255/(states-1))
+	}
+	return greys
+}
+
+// get the state for every grey level, the nearest one for levels that no state uses
+func greyStates(states int) [256]byte {
+	var lookup [256]byte
+	greys := stateGreys(states)
+	for grey := range lookup {
+		nearest := 256
+		for state, g := range greys {
+			distance := grey - int(g)
+			if distance < 0 {
+				distance = -distance
+			}
+			if distance < nearest {
+				nearest = distance
+				lookup[grey] = byte(state)
+			}
+		}
+	}
+	return lookup
+}
+
+// states of WireworldRule
+const (
+	WireEmpty     byte = iota
+	WireHead           // electron head
+	WireTail           // electron tail
+	WireConductor      // copper wire
+)
+
+// WireworldRule is Brian Silverman's Wireworld, where electrons flow along wires.
+// A head becomes a tail, a tail becomes wire, and wire becomes a head when 1 or 2 of its neighbours are heads.
+type WireworldRule struct{}
+
+func (rule WireworldRule) States() int {
+	return 4
+}
+
+func (rule WireworldRule) Neighbourhood() []util.Cell {
+	return MooreNeighbourhood(1)
+}
+
+func (rule WireworldRule) Next(state byte, neighbours []byte) byte {
+	switch state {
+	case WireHead:
+		return WireTail
+	case WireTail:
+		return WireConductor
+	case WireConductor:
+		heads := 0
+		for _, neighbour := range neighbours {
+			if neighbour == WireHead {
+				heads++
+			}
+		}
+		if heads == 1 || heads == 2 {
+			return WireHead
+		}
+		return WireConductor
+	}
+	return WireEmpty
+}
+
+// WireworldPalette is the usual colouring of Wireworld: black for empty cells, blue heads, red tails and yellow wire
+var WireworldPalette = []color.RGBA{
+	WireEmpty:     {R: 0x00, G: 0x00, B: 0x00, A: 0xFF},
+	WireHead:      {R: 0x00, G: 0x00, B: 0xFF, A: 0xFF},
+	WireTail:      {R: 0xFF, G: 0x00, B: 0x00, A: 0xFF},
+	WireConductor: {R: 0xFF, G: 0xFF, B: 0x00, A: 0xFF},
+}
+
+func (rule WireworldRule) Palette() []color.RGBA {
+	return WireworldPalette
+}
+
+func (rule WireworldRule) String() string {
+	return "Wireworld"
+}
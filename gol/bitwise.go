@@ -0,0 +1,230 @@
+package gol
+
+import (
+	"math/bits"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// bitEngine packs 64 cells into each uint64 and evolves a whole word at a time with bitwise adders.
+// Bit b of word k in a row is the cell in column 64k+b. Bits beyond the width of the world are always 0.
+type bitEngine struct {
+	p        Params
+	rule     LifeRule
+	t        topology
+	c        distributorChannels
+	words    int    // number of words in a row
+	lastBit  uint   // position of the last column in the last word of a row
+	lastMask uint64 // bits of the last word that hold cells
+	rows     [][]uint64
+	next     [][]uint64
+	strips   []HorSlice
+}
+
+// a packed row together with the cells just beyond its left and right edges
+type bitRow struct {
+	words      []uint64
+	west, east uint64 // 1 if the cell in column -1 / column width is alive
+}
+
+func newBitEngine(p Params, world [][]byte, rule LifeRule, t topology, c distributorChannels) *bitEngine {
+	words := (p.ImageWidth + 63) / 64
+	e := &bitEngine{
+		p:        p,
+		rule:     rule,
+		t:        t,
+		c:        c,
+		words:    words,
+		lastBit:  uint((p.ImageWidth - 1) % 64),
+		lastMask: ^uint64(0) >> uint(64*words-p.ImageWidth),
+		rows:     createNewBitSlice(p.ImageHeight, words),
+		next:     createNewBitSlice(p.ImageHeight, words),
+		strips:   splitRows(p.ImageHeight, p.Threads),
+	}
+	for i, row := range world {
+		for j, cell := range row {
+			if cell == 0xFF {
+				e.rows[i][j/64] |= 1 << uint(j%64)
+			}
+		}
+	}
+	return e
+}
+
+// parameterizable 2D slice of words (rows x words)
+func createNewBitSlice(rows, words int) [][]uint64 {
+	world := make([][]uint64, rows)
+	for i := range world {
+		world[i] = make([]uint64, words)
+	}
+	return world
+}
+
+// check whether the cell at (row, column) is alive, which may lie beyond the edges of the world
+func (e *bitEngine) alive(row, column int) uint64 {
+	if row < 0 || row >= e.p.ImageHeight || column < 0 || column >= e.p.ImageWidth {
+		var ok bool
+		row, column, ok = e.t.wrap(row, column)
+		if !ok {
+			return 0
+		}
+	}
+	return (e.rows[row][column/64] >> uint(column%64)) & 1
+}
+
+// get row r with its edge cells. Rows beyond the top or bottom are built cell by cell through the topology
+func (e *bitEngine) row(r int) bitRow {
+	row := bitRow{west: e.alive(r, -1), east: e.alive(r, e.p.ImageWidth)}
+	if r >= 0 && r < e.p.ImageHeight {
+		row.words = e.rows[r]
+		return row
+	}
+	row.words = make([]uint64, e.words)
+	for j := 0; j < e.p.ImageWidth; j++ {
+		row.words[j/64] |= e.alive(r, j) << uint(j%64)
+	}
+	return row
+}
+
+// line up word k of the row with its west and east neighbours
+func (r bitRow) shifted(k int, lastBit uint) (west, centre, east uint64) {
+	centre = r.words[k]
+	west = centre << 1
+	if k > 0 {
+		west |= r.words[k-1] >> 63
+	} else {
+		west |= r.west
+	}
+	east = centre >> 1
+	if k < len(r.words)-1 {
+		east |= r.words[k+1] << 63
+	} else {
+		east |= r.east << lastBit
+	}
+	return west, centre, east
+}
+
+// add three bits in every position
+func fullAdd(a, b, c uint64) (sum, carry uint64) {
+	half := a ^ b
+	return half ^ c, (a & b) | (half & c)
+}
+
+// select the positions whose 4-bit neighbour count (c0 least significant) equals n
+func countEquals(n int, c0, c1, c2, c3 uint64) uint64 {
+	match := ^uint64(0)
+	for i, bit := range [4]uint64{c0, c1, c2, c3} {
+		if n>>uint(i)&1 == 1 {
+			match &= bit
+		} else {
+			match &= ^bit
+		}
+	}
+	return match
+}
+
+// apply the rule to a word of cells given the bits of their neighbour counts
+func nextWord(rule LifeRule, cells, c0, c1, c2, c3 uint64) uint64 {
+	var next uint64
+	for n := 0; n <= 8; n++ {
+		born, survives := rule.Birth[n], rule.Survival[n]
+		if !born && !survives {
+			continue
+		}
+		match := countEquals(n, c0, c1, c2, c3)
+		switch {
+		case born && survives:
+			next |= match
+		case born:
+			next |= match &^ cells
+		default:
+			next |= match & cells
+		}
+	}
+	return next
+}
+
+// compute the next generation of a word of cells (h) from the rows above, here and below,
+// each lined up with their west and east neighbours
+func evolveWord(rule LifeRule, aw, a, ae, hw, h, he, bw, b, be uint64) uint64 {
+	// count the eight neighbours into bits c0-c3
+	aboveSum, aboveCarry := fullAdd(aw, a, ae)
+	belowSum, belowCarry := fullAdd(bw, b, be)
+	hereSum, hereCarry := hw^he, hw&he
+	c0, twos := fullAdd(aboveSum, hereSum, belowSum)
+	twosSum, fours := fullAdd(aboveCarry, hereCarry, belowCarry)
+	c1, moreFours := twosSum^twos, twosSum&twos
+	c2, c3 := fours^moreFours, fours&moreFours
+	return nextWord(rule, h, c0, c1, c2, c3)
+}
+
+// compute the next generation of a row into e.next, sending the changes to flips
+func (e *bitEngine) evolveRow(i int, flips *flipSender) {
+	above, here, below := e.row(i-1), e.row(i), e.row(i+1)
+	for k := 0; k < e.words; k++ {
+		aw, a, ae := above.shifted(k, e.lastBit)
+		hw, h, he := here.shifted(k, e.lastBit)
+		bw, b, be := below.shifted(k, e.lastBit)
+
+		next := evolveWord(e.rule, aw, a, ae, hw, h, he, bw, b, be)
+		if k == e.words-1 {
+			next &= e.lastMask
+		}
+		e.next[i][k] = next
+
+		// cellFlipped event for every changed bit
+		for flipped := next ^ h; flipped != 0; flipped &= flipped - 1 {
+			j := 64*k + bits.TrailingZeros64(flipped)
+			flips.flip(util.Cell{X: j, Y: i})
+		}
+	}
+}
+
+// run one worker per strip, then swap the buffers
+func (e *bitEngine) step(turn, turns int) int {
+	var waitgroup sync.WaitGroup
+	for _, strip := range e.strips {
+		waitgroup.Add(1)
+		go func(strip HorSlice) {
+			flips := newFlipSender(e.p, e.c, turn)
+			for i := strip.startRow; i < strip.endRow; i++ {
+				e.evolveRow(i, flips)
+			}
+			flips.send()
+			waitgroup.Done()
+		}(strip)
+	}
+	waitgroup.Wait()
+	e.rows, e.next = e.next, e.rows
+	return 1
+}
+
+func (e *bitEngine) world() [][]byte {
+	world := createNewSlice(e.p.ImageHeight, e.p.ImageWidth)
+	for i, row := range e.rows {
+		for j := range world[i] {
+			if (row[j/64]>>uint(j%64))&1 == 1 {
+				world[i][j] = 0xFF
+			}
+		}
+	}
+	return world
+}
+
+func (e *bitEngine) aliveCount() int {
+	count := 0
+	for _, row := range e.rows {
+		for _, word := range row {
+			count += bits.OnesCount64(word)
+		}
+	}
+	return count
+}
+
+func (e *bitEngine) aliveCells() []util.Cell {
+	return getAliveCells(e.world())
+}
+
+// nothing runs between steps
+func (e *bitEngine) stop() {}
@@ -0,0 +1,90 @@
+package gol
+
+import "fmt"
+
+// Boundary selects how the edges of the world are joined together.
+type Boundary int
+
+const (
+	Torus        Boundary = iota // left/right and top/bottom edges wrap around
+	DeadEdges                    // bounded plane, cells beyond the edges are permanently dead
+	KleinBottle                  // left/right edges wrap, top/bottom edges wrap with a horizontal flip
+	CrossSurface                 // both pairs of edges wrap with a flip
+	TwistedTorus                 // torus with the top/bottom edges joined after a horizontal shift of Params.Shift
+)
+
+// names used by ParseBoundary and the -boundary flag
+var boundaryNames = map[string]Boundary{
+	"torus":   Torus,
+	"dead":    DeadEdges,
+	"klein":   KleinBottle,
+	"cross":   CrossSurface,
+	"twisted": TwistedTorus,
+}
+
+// ParseBoundary gets the Boundary for one of "torus", "dead", "klein", "cross" or "twisted".
+func ParseBoundary(name string) (Boundary, error) {
+	boundary, ok := boundaryNames[name]
+	if !ok {
+		return Torus, fmt.Errorf("unknown boundary %q", name)
+	}
+	return boundary, nil
+}
+
+func (boundary Boundary) String() string {
+	switch boundary {
+	case Torus:
+		return "Torus"
+	case DeadEdges:
+		return "Dead edges"
+	case KleinBottle:
+		return "Klein bottle"
+	case CrossSurface:
+		return "Cross-surface"
+	case TwistedTorus:
+		return "Twisted torus"
+	default:
+		return "Incorrect Boundary"
+	}
+}
+
+// the shape of the world, used to find neighbours beyond its edges
+type topology struct {
+	width, height int
+	boundary      Boundary
+	shift         int
+}
+
+func newTopology(p Params) topology {
+	return topology{width: p.ImageWidth, height: p.ImageHeight, boundary: p.Boundary, shift: p.Shift}
+}
+
+// map a position beyond the edges back into the world. ok is false when the position is permanently dead.
+// Crossing top/bottom is applied before crossing left/right, which decides the corners of the cross-surface.
+func (t topology) wrap(row, column int) (int, int, bool) {
+	switch t.boundary {
+	case DeadEdges:
+		if row < 0 || row >= t.height || column < 0 || column >= t.width {
+			return row, column, false
+		}
+	case KleinBottle, CrossSurface:
+		if row < 0 || row >= t.height {
+			column = t.width - 1 - column
+		}
+		if t.boundary == CrossSurface && (column < 0 || column >= t.width) {
+			row = t.height - 1 - row
+		}
+	case TwistedTorus:
+		if row < 0 {
+			column -= t.shift
+		} else if row >= t.height {
+			column += t.shift
+		}
+	}
+	return wrapIndex(row, t.height), wrapIndex(column, t.width), true
+}
+
+// modulo that stays positive for negative indices
+func wrapIndex(i, size int) int {
+	return ((i % size) + size) % size
+}
@@ -0,0 +1,520 @@
+package gol
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// how often the broker checks its workers are alive, and how long they have to answer
+const (
+	heartbeatInterval = 500 * time.Millisecond
+	heartbeatTimeout  = 2 * time.Second
+)
+
+// errWorkerFailed is returned when a worker stops answering during a call to every worker
+var errWorkerFailed = errors.New("a worker failed")
+
+// remoteWorker is a worker registered with the broker
+type remoteWorker struct {
+	address string
+	client  *rpc.Client
+	dead    bool
+}
+
+// brokerServer is the RPC service of the broker, which runs a world handed to it by a controller
+// on the workers that have registered with it. It splits the world into one strip per worker,
+// and each turn only starts the turn on every worker and waits for them all to finish it,
+// as the workers exchange the cells beyond the edges of their strips themselves.
+// The strips are gathered from the workers every Params.CheckpointTurns turns as a checkpoint.
+// When a worker fails the run rolls back to the checkpoint, split between the workers left
+// and any that have joined since, and computes the lost turns again.
+// With Params.Gather it instead sends each worker its strip with the cells around it every turn and gathers the new strips.
+//
+// The broker computes the turns on its own, without waiting for the controller. The workers send back the changes
+// to their strips each turn, which are merged into one frame until the controller fetches it, see FrameResponse.
+// A controller can leave the run going and another can attach to it later, see AttachResponse.
+type brokerServer struct {
+	listener net.Listener
+
+	// the registered workers, which can join or fail at any time
+	poolMu sync.Mutex
+	joined *sync.Cond
+	pool   []*remoteWorker
+
+	mu sync.Mutex
+	// the run, after turn turns. The world is only kept when the strips are gathered every turn
+	p        Params
+	t        topology
+	radius   int
+	world    [][]byte
+	turn     int
+	strips   []HorSlice
+	assigned []*remoteWorker // assigned[i] computes strip i
+	running  bool
+	paused   bool
+	pauseAt  int // the turn the run is paused after for a PauseRequest with a Turn, if not 0
+	err      error
+	id       int // counts the runs, so that the goroutine of a stopped run knows to return
+	// broadcast whenever a turn is completed or the run is paused, resumed or stopped
+	changed *sync.Cond
+	// the world after checkpointTurn turns, which the run rolls back to
+	checkpoint     [][]byte
+	checkpointTurn int
+	// the XOR of the changes made to the world since the last frame, up to reached turns.
+	// Turns computed again after rolling back are already in it
+	pending []byte
+	sent    int
+	reached int
+}
+
+// ServeBroker serves the calls of workers and controllers on listener until it is closed,
+// or a controller shuts the broker down.
+func ServeBroker(listener net.Listener) {
+	b := &brokerServer{listener: listener}
+	b.joined = sync.NewCond(&b.poolMu)
+	b.changed = sync.NewCond(&b.mu)
+	server := rpc.NewServer()
+	util.Check(server.RegisterName("Broker", b))
+	stop := make(chan bool)
+	go b.heartbeat(stop)
+	server.Accept(listener)
+	close(stop)
+}
+
+// check every worker answers in time, until stop is closed
+func (b *brokerServer) heartbeat(stop <-chan bool) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		for _, w := range b.alive() {
+			go func(w *remoteWorker) {
+				call := w.client.Go("Worker.Heartbeat", Empty{}, new(Empty), nil)
+				select {
+				case <-call.Done:
+					if call.Error != nil {
+						b.fail(w)
+					}
+				case <-time.After(heartbeatTimeout):
+					b.fail(w)
+				}
+			}(w)
+		}
+	}
+}
+
+// mark a worker as failed, closing its connection so that any calls waiting on it return
+func (b *brokerServer) fail(w *remoteWorker) {
+	b.poolMu.Lock()
+	defer b.poolMu.Unlock()
+	if !w.dead {
+		w.dead = true
+		w.client.Close()
+	}
+}
+
+// get the workers that have not failed
+func (b *brokerServer) alive() []*remoteWorker {
+	b.poolMu.Lock()
+	defer b.poolMu.Unlock()
+	return b.aliveLocked()
+}
+
+// Register connects to a new worker, which is given a strip from the next run or when a worker fails
+func (b *brokerServer) Register(req RegisterRequest, res *Empty) error {
+	client, err := rpc.Dial("tcp", req.Address)
+	if err != nil {
+		return err
+	}
+	b.poolMu.Lock()
+	b.pool = append(b.pool, &remoteWorker{address: req.Address, client: client})
+	b.joined.Broadcast()
+	b.poolMu.Unlock()
+	return nil
+}
+
+// Start a run from the world of a controller, splitting it between the workers, and compute its turns
+func (b *brokerServer) Start(req StartRequest, res *Empty) error {
+	rule, err := selectRule(req.Params)
+	if err != nil {
+		return err
+	}
+	if err := checkRemote(req.Params, rule); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.alive()) == 0 {
+		return errors.New("no workers have registered with the broker")
+	}
+	b.stop()
+	b.p, b.t = req.Params, newTopology(req.Params)
+	if b.p.CheckpointTurns <= 0 {
+		b.p.CheckpointTurns = 100
+	}
+	b.radius = neighbourhoodRadius(rule.Neighbourhood())
+	b.checkpoint, b.checkpointTurn = req.World, 0
+	b.pending = make([]byte, b.p.ImageWidth*b.p.ImageHeight)
+	b.sent, b.reached = 0, 0
+	b.paused, b.err = false, nil
+	if err := b.restore(); err != nil {
+		return err
+	}
+	b.running = true
+	go b.run(b.id)
+	return nil
+}
+
+// compute the turns of run id until they are all done, it fails or another run starts
+func (b *brokerServer) run(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.id == id && b.turn < b.p.Turns && b.err == nil {
+		if b.paused {
+			b.changed.Wait()
+			continue
+		}
+		b.err = b.advance(b.turn + 1)
+		if b.pauseAt > 0 && b.turn >= b.pauseAt {
+			b.paused, b.pauseAt = true, 0
+		}
+		b.changed.Broadcast()
+		// let the controller's calls in between turns
+		b.mu.Unlock()
+		b.mu.Lock()
+	}
+}
+
+// Frame waits for the next frame of the run, see FrameResponse
+func (b *brokerServer) Frame(req Empty, res *FrameResponse) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.running && b.err == nil && b.reached == b.sent && b.reached < b.p.Turns && !b.paused {
+		b.changed.Wait()
+	}
+	switch {
+	case !b.running:
+		return errors.New("the broker has not been given a world")
+	case b.err != nil:
+		return b.err
+	case b.reached == b.sent && !b.paused:
+		return errors.New("the run has finished")
+	}
+	diff, err := compressDiff(b.pending)
+	if err != nil {
+		return err
+	}
+	res.Turn, res.Diff = b.reached, diff
+	for k := range b.pending {
+		b.pending[k] = 0
+	}
+	b.sent = b.reached
+	return nil
+}
+
+// Pause or resume the run, see PauseRequest
+func (b *brokerServer) Pause(req PauseRequest, res *Empty) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !req.Paused || req.Turn <= 0 {
+		b.paused = req.Paused
+		b.changed.Broadcast()
+		return nil
+	}
+	b.pauseAt = req.Turn
+	for !b.running || b.turn < req.Turn {
+		if b.running && b.err != nil {
+			return b.err
+		}
+		if b.running && b.turn == b.p.Turns {
+			return errors.New("the run has finished")
+		}
+		b.changed.Wait()
+	}
+	b.paused, b.pauseAt = true, 0
+	b.changed.Broadcast()
+	return nil
+}
+
+// Attach a new controller to the run, which gets the world and carries on with the frames from there
+func (b *brokerServer) Attach(req Empty, res *AttachResponse) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.running {
+		return errors.New("the broker has not been given a world")
+	}
+	// the run goroutine only lets calls in between turns, when the turn is the last one reached
+	world, err := b.currentWorld()
+	if err != nil {
+		return err
+	}
+	res.Params, res.World, res.Turn, res.Paused = b.p, world, b.turn, b.paused
+	for k := range b.pending {
+		b.pending[k] = 0
+	}
+	b.sent = b.reached
+	return nil
+}
+
+// get the world after the current turn, computing the turn again if a worker fails
+func (b *brokerServer) currentWorld() ([][]byte, error) {
+	if b.p.Gather {
+		return b.world, nil
+	}
+	target := b.turn
+	for {
+		world, err := b.gatherStrips()
+		if err == errWorkerFailed {
+			if err := b.restore(); err != nil {
+				return nil, err
+			}
+			if err := b.advance(target); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return world, err
+	}
+}
+
+// split the checkpoint between the workers that are alive, waiting for one to join if they have all failed
+func (b *brokerServer) restore() error {
+	for {
+		b.poolMu.Lock()
+		for len(b.aliveLocked()) == 0 {
+			b.joined.Wait()
+		}
+		workers := b.aliveLocked()
+		b.poolMu.Unlock()
+
+		strips := len(workers)
+		if strips > b.p.ImageHeight {
+			strips = b.p.ImageHeight
+		}
+		b.strips = splitRows(b.p.ImageHeight, strips)
+		b.assigned = workers[:strips]
+		b.world, b.turn = b.checkpoint, b.checkpointTurn
+		workerStrips := make([]WorkerStrip, strips)
+		for i, strip := range b.strips {
+			workerStrips[i] = WorkerStrip{Address: b.assigned[i].address, StartRow: strip.startRow, EndRow: strip.endRow}
+		}
+		_, err := b.callAll("Worker.Start", func(i int) interface{} {
+			return StripRequest{Params: b.p, ID: i, Strips: workerStrips, Buffer: b.halo(b.strips[i])}
+		}, func() interface{} { return new(Empty) })
+		if err == errWorkerFailed {
+			continue
+		}
+		if !b.p.Gather {
+			b.world = nil
+		}
+		return err
+	}
+}
+
+// get the workers that have not failed, with poolMu held
+func (b *brokerServer) aliveLocked() []*remoteWorker {
+	var alive []*remoteWorker
+	for _, w := range b.pool {
+		if !w.dead {
+			alive = append(alive, w)
+		}
+	}
+	return alive
+}
+
+// compute turns until the run is after target turns, rolling back to the checkpoint whenever a worker fails
+func (b *brokerServer) advance(target int) error {
+	for b.turn < target {
+		err := b.step()
+		if err == errWorkerFailed {
+			err = b.restore()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compute the next turn, then save a checkpoint if one is due
+func (b *brokerServer) step() error {
+	b.poolMu.Lock()
+	failed := false
+	for _, w := range b.assigned {
+		failed = failed || w.dead
+	}
+	b.poolMu.Unlock()
+	if failed {
+		return errWorkerFailed
+	}
+	if b.p.Gather {
+		calls, err := b.callAll("Worker.Evolve", func(i int) interface{} {
+			return EvolveRequest{Turn: b.turn, Buffer: b.halo(b.strips[i])}
+		}, func() interface{} { return new(StripResponse) })
+		if err != nil {
+			return err
+		}
+		world := gather(calls)
+		if b.turn == b.reached {
+			for k, change := range xorRows(make([]byte, 0, len(b.pending)), b.world, world) {
+				b.pending[k] ^= change
+			}
+		}
+		b.world = world
+		b.turn++
+		if b.turn > b.reached {
+			b.reached = b.turn
+		}
+		// the broker has the whole world every turn anyway
+		b.checkpoint, b.checkpointTurn = b.world, b.turn
+		return nil
+	}
+	calls, err := b.callAll("Worker.Step", func(i int) interface{} {
+		return StepRequest{Turn: b.turn}
+	}, func() interface{} { return new(DiffResponse) })
+	if err != nil {
+		return err
+	}
+	if b.turn == b.reached {
+		for i, call := range calls {
+			strip := b.strips[i]
+			offset, size := strip.startRow*b.p.ImageWidth, (strip.endRow-strip.startRow)*b.p.ImageWidth
+			diff, err := decompressDiff(call.Reply.(*DiffResponse).Diff, size)
+			if err != nil {
+				return err
+			}
+			for k, change := range diff {
+				b.pending[offset+k] ^= change
+			}
+		}
+	}
+	b.turn++
+	if b.turn > b.reached {
+		b.reached = b.turn
+	}
+	if b.turn%b.p.CheckpointTurns == 0 {
+		world, err := b.gatherStrips()
+		if err != nil {
+			return err
+		}
+		b.checkpoint, b.checkpointTurn = world, b.turn
+	}
+	return nil
+}
+
+// get the rows of a strip surrounded by the cells the topology puts beyond its edges, as in a worker's buffers
+func (b *brokerServer) halo(strip HorSlice) [][]byte {
+	buffer := createNewSlice(strip.endRow-strip.startRow+2*b.radius, b.p.ImageWidth+2*b.radius)
+	for i := range buffer {
+		for j := range buffer[i] {
+			// permanently dead cells are left as 0
+			if row, column, ok := b.t.wrap(strip.startRow+i-b.radius, j-b.radius); ok {
+				buffer[i][j] = b.world[row][column]
+			}
+		}
+	}
+	return buffer
+}
+
+// get the strip of every worker
+func (b *brokerServer) gatherStrips() ([][]byte, error) {
+	calls, err := b.callAll("Worker.Strip", func(i int) interface{} {
+		return Empty{}
+	}, func() interface{} { return new(StripResponse) })
+	if err != nil {
+		return nil, err
+	}
+	return gather(calls), nil
+}
+
+// Stop the run, forgetting its world
+func (b *brokerServer) Stop(req Empty, res *Empty) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stop()
+	return nil
+}
+
+// Shutdown stops the run and every worker, then stops serving
+func (b *brokerServer) Shutdown(req Empty, res *Empty) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stop()
+	for _, w := range b.alive() {
+		// a worker that cannot be reached is already gone
+		w.client.Call("Worker.Shutdown", Empty{}, new(Empty))
+		b.fail(w)
+	}
+	return b.listener.Close()
+}
+
+// stop the run, with mu held
+func (b *brokerServer) stop() {
+	b.id++
+	b.world, b.checkpoint, b.pending, b.strips, b.assigned, b.running = nil, nil, nil, nil, nil, false
+	b.changed.Broadcast()
+}
+
+// call a method on every assigned worker at once, with the arguments for its strip, and wait for them all.
+// As soon as one call fails the turn is aborted on every worker, as the others could be waiting for its halo cells.
+// Returns errWorkerFailed if any worker could not be reached, after marking it as failed.
+func (b *brokerServer) callAll(method string, args func(i int) interface{}, reply func() interface{}) ([]*rpc.Call, error) {
+	done := make(chan *rpc.Call, len(b.assigned))
+	calls := make([]*rpc.Call, len(b.assigned))
+	for i, w := range b.assigned {
+		calls[i] = w.client.Go(method, args(i), reply(), done)
+	}
+	var err error
+	for range calls {
+		call := <-done
+		if call.Error != nil && err == nil {
+			err = call.Error
+			b.abort()
+		}
+	}
+	failed := false
+	for i, call := range calls {
+		// errors returned by the worker itself mean it is still there
+		if _, ok := call.Error.(rpc.ServerError); call.Error != nil && !ok {
+			b.fail(b.assigned[i])
+			failed = true
+		}
+	}
+	if failed {
+		return calls, errWorkerFailed
+	}
+	if err != nil {
+		return calls, fmt.Errorf("%v: %v", method, err)
+	}
+	return calls, nil
+}
+
+// abort the turn on every assigned worker, waiting for them all to hear so that it cannot abort the next run
+func (b *brokerServer) abort() {
+	calls := make([]*rpc.Call, len(b.assigned))
+	for i, w := range b.assigned {
+		calls[i] = w.client.Go("Worker.Abort", Empty{}, new(Empty), nil)
+	}
+	for _, call := range calls {
+		<-call.Done
+	}
+}
+
+// put the strips returned by calls to the workers together into the world
+func gather(calls []*rpc.Call) [][]byte {
+	var world [][]byte
+	for _, call := range calls {
+		world = append(world, call.Reply.(*StripResponse).Strip...)
+	}
+	return world
+}
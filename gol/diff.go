@@ -0,0 +1,41 @@
+package gol
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+// append the XOR of the state of every cell in the rows of a and b, row by row, to diff.
+// Applying it to a with another XOR gives b, and the diffs of turns one after another XOR together into one.
+func xorRows(diff []byte, a, b [][]byte) []byte {
+	for i, row := range a {
+		for j, state := range row {
+			diff = append(diff, state^b[i][j])
+		}
+	}
+	return diff
+}
+
+// compress a diff, which is mostly zeros as most cells do not change
+func compressDiff(diff []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer, err := flate.NewWriter(&buffer, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(diff); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// decompress a diff of size cells
+func decompressDiff(data []byte, size int) ([]byte, error) {
+	diff := make([]byte, size)
+	_, err := io.ReadFull(flate.NewReader(bytes.NewReader(data)), diff)
+	return diff, err
+}
@@ -44,6 +44,13 @@ func (c *HSliceChannel) Send(value HorSlice, block bool) {
 	c.cond.L.Unlock()
 }
 
+// Full reports whether a non-blocking Send would be dropped
+func (c *HSliceChannel) Full() bool {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	return len(c.value) == c.capacity
+}
+
 func (c *HSliceChannel) Receive() (v HorSlice) {
 	c.cond.L.Lock()
 	for len(c.value) == 0 {
@@ -60,10 +67,67 @@ type distributorChannels struct {
 	ioCommand  chan<- ioCommand
 	ioIdle     <-chan bool
 	ioFilename chan<- string
+	ioSize     chan<- imageSize
 	ioOutput   chan<- uint8
 	ioInput    <-chan uint8
 }
 
+// flipSender sends the cells flipped by one worker in a turn, either straight away as CellFlipped events
+// or all together as one CellsFlipped event when Params.BatchFlips is set.
+// When Params.Colour is set the changes of state are sent as one CellsChanged event instead.
+type flipSender struct {
+	events  chan<- Event
+	batch   bool
+	colour  bool
+	turn    int
+	cells   []util.Cell
+	changed []util.Cell
+	states  []byte
+	// the states that count as alive when a cell changes state, see aliveStates
+	alive [256]bool
+}
+
+func newFlipSender(p Params, c distributorChannels, turn int) *flipSender {
+	return &flipSender{events: c.events, batch: p.BatchFlips, colour: p.Colour, turn: turn, alive: [256]bool{1: true}}
+}
+
+// make a flipSender for the changes of state of cells under a rule
+func newStateSender(p Params, c distributorChannels, turn int, rule Rule) *flipSender {
+	f := newFlipSender(p, c, turn)
+	f.alive = aliveStates(rule)
+	return f
+}
+
+// a cell changed state, which is a flip if it became alive or stopped being alive
+func (f *flipSender) change(cell util.Cell, from, to byte) {
+	if f.colour {
+		f.changed = append(f.changed, cell)
+		f.states = append(f.states, to)
+	} else if f.alive[from] != f.alive[to] {
+		f.flip(cell)
+	}
+}
+
+func (f *flipSender) flip(cell util.Cell) {
+	if f.batch {
+		f.cells = append(f.cells, cell)
+	} else {
+		f.events <- CellFlipped{f.turn, cell}
+	}
+}
+
+// send the batch, if there is one. Must be called before the turn is completed
+func (f *flipSender) send() {
+	if len(f.cells) > 0 {
+		f.events <- CellsFlipped{f.turn, f.cells}
+		f.cells = nil
+	}
+	if len(f.changed) > 0 {
+		f.events <- CellsChanged{f.turn, f.changed, f.states}
+		f.changed, f.states = nil, nil
+	}
+}
+
 // parameterizable 2D slice creator (rows x columns)
 func createNewSlice(rows, columns int) [][]byte {
 	world := make([][]byte, rows)
@@ -73,63 +137,89 @@ func createNewSlice(rows, columns int) [][]byte {
 	return world
 }
 
-// count the number of neighbours that a particular cell has in the world
-func getNeighbourCount(world [][]byte, row, column int, p Params) int {
-	var alive byte = 0
-	// positions of neighbouring cells relative to current cell
-	offsets := []util.Cell{
-		{X: -1, Y: -1},
-		{X: -1, Y: 0},
-		{X: -1, Y: 1},
-		{X: 0, Y: -1},
-		{X: 0, Y: 1},
-		{X: 1, Y: -1},
-		{X: 1, Y: 0},
-		{X: 1, Y: 1},
+// the original byte-per-cell engine, splitting the world into horizontal strips between a pool of workers
+// that live for the whole run and skip the tiles where nothing can change.
+// The engine only coordinates the turns, the workers exchange the edges of their strips themselves.
+// Any Rule can be used, the workers hold the state of each cell and the world is given as their grey levels.
+type stripEngine struct {
+	p       Params
+	greys   []byte
+	alive   [256]bool
+	tiles   *tileActivity
+	workers []*worker
+	done    chan bool
+}
+
+func newStripEngine(p Params, world [][]byte, rule Rule, t topology, c distributorChannels) *stripEngine {
+	lookup := greyStates(rule.States())
+	states := createNewSlice(p.ImageHeight, p.ImageWidth)
+	for i, row := range world {
+		for j, grey := range row {
+			states[i][j] = lookup[grey]
+		}
 	}
-	for _, offset := range offsets {
-		actualRow := (row + offset.X) & (p.ImageHeight - 1)
-		actualCol := (column + offset.Y) & (p.ImageWidth - 1)
-		alive += (world[actualRow][actualCol] >> 7)
+	tiles := newTileActivity(p.ImageWidth, p.ImageHeight, neighbourhoodRadius(rule.Neighbourhood()))
+	// with random deaths any cell can change, whatever happened around it
+	tiles.always = p.Stochastic
+	done := make(chan bool)
+	return &stripEngine{
+		p:       p,
+		greys:   stateGreys(rule.States()),
+		alive:   aliveStates(rule),
+		tiles:   tiles,
+		workers: startWorkers(p, states, rule, t, tiles, c, done),
+		done:    done,
 	}
-	return int(alive)
 }
 
-// apply GOL rules and return the result for given cell
-func getNextCell(slice HorSlice, i, j, neighbourCount int) uint8 {
-	if neighbourCount < 2 || neighbourCount > 3 {
-		return 0x00
-	} else if slice.grid[i][j] == 0x00 && neighbourCount == 3 {
-		return 0xFF
-	} else {
-		return slice.grid[i][j]
+// start the turn on every worker and wait for them all to finish it
+func (e *stripEngine) step(turn, turns int) int {
+	e.tiles.update()
+	for _, w := range e.workers {
+		w.work <- turn
 	}
+	for range e.workers {
+		<-e.done
+	}
+	return 1
 }
 
-// parameterizable evolve slice.grid
-func evolveSlice(slice HorSlice, p Params, c distributorChannels, turn int) [][]byte {
-	// create empty slice
-	newSlice := createNewSlice(slice.endRow-slice.startRow, p.ImageWidth)
-	// iterate through cells of slice of the oldGrid
-	for i := slice.startRow; i < slice.endRow; i++ {
-		for j := 0; j < p.ImageWidth; j++ {
-			neighbourCount := getNeighbourCount(slice.grid, i, j, p)
-			// get new value for cell and append to newSlice
-			updatedCell := getNextCell(slice, i, j, neighbourCount)
-			newSlice[i-slice.startRow][j] = updatedCell
-			// cellFlipped event
-			if updatedCell != slice.grid[i][j] {
-				c.events <- CellFlipped{turn, util.Cell{X: j, Y: i}}
+// gather the strips of the workers, which are idle between turns
+func (e *stripEngine) world() [][]byte {
+	world := createNewSlice(e.p.ImageHeight, e.p.ImageWidth)
+	for _, w := range e.workers {
+		for i := w.slice.startRow; i < w.slice.endRow; i++ {
+			for j, state := range w.cur[i-w.slice.startRow+w.radius][w.radius : e.p.ImageWidth+w.radius] {
+				world[i][j] = e.greys[state]
+			}
+		}
+	}
+	return world
+}
+
+func (e *stripEngine) aliveCount() int {
+	return len(e.aliveCells())
+}
+
+// get the cells in any alive state, from the strips of the workers
+func (e *stripEngine) aliveCells() []util.Cell {
+	var aliveCells []util.Cell
+	for _, w := range e.workers {
+		for i := w.slice.startRow; i < w.slice.endRow; i++ {
+			for j, state := range w.cur[i-w.slice.startRow+w.radius][w.radius : e.p.ImageWidth+w.radius] {
+				if e.alive[state] {
+					aliveCells = append(aliveCells, util.Cell{X: j, Y: i})
+				}
 			}
 		}
 	}
-	return newSlice
+	return aliveCells
 }
 
-// create a worker assigned to a segment of the image
-func worker(slice HorSlice, p Params, output *HSliceChannel, c distributorChannels, turn int) {
-	newSlice := evolveSlice(slice, p, c, turn)
-	output.Send(HorSlice{newSlice, slice.startRow, slice.endRow}, true)
+func (e *stripEngine) stop() {
+	for _, w := range e.workers {
+		close(w.work)
+	}
 }
 
 // get a list of the alive cells existing in the world
@@ -162,7 +252,12 @@ func getAliveCellsCount(world [][]byte) int {
 // generate PGM file using ioCommand
 func generatePGM(p Params, c distributorChannels, world [][]byte, turns int) {
 	c.ioCommand <- ioOutput
-	c.ioFilename <- (strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(p.ImageHeight) + "x" + strconv.Itoa(turns))
+	c.ioFilename <- imageName(p) + "x" + strconv.Itoa(turns)
+	size := imageSize{height: len(world)}
+	if len(world) > 0 {
+		size.width = len(world[0])
+	}
+	c.ioSize <- size
 
 	for _, row := range world {
 		for _, cell := range row {
@@ -171,20 +266,22 @@ func generatePGM(p Params, c distributorChannels, world [][]byte, turns int) {
 	}
 }
 
-// send the AliveCellsCount event
-func checkTicker(ticker *time.Ticker, world [][]byte, turn int, c distributorChannels) {
+// send the AliveCellsCount event, and the ColourCellsCount event for a ColourRule
+func checkTicker(ticker *time.Ticker, eng engine, rule Rule, turn int, c distributorChannels) {
 	select {
 	case <-ticker.C:
-		alive := getAliveCellsCount(world)
+		alive := eng.aliveCount()
 		c.events <- AliveCellsCount{CellsCount: alive, CompletedTurns: turn}
+		if colours, ok := rule.(ColourRule); ok {
+			c.events <- ColourCellsCount{CompletedTurns: turn, CellsCounts: countColours(eng.world(), colours)}
+		}
 	default:
 	}
 
 }
 
-// parse keypresses and execute the different actions
-func keypressParser(p Params, c distributorChannels, kp <-chan rune, turnChan *channels.IntChannel, worldChan *HSliceChannel, wg *sync.WaitGroup, quit *channels.BoolChannel) {
-	paused := false
+// parse keypresses and execute the different actions, starting paused if the run already is
+func keypressParser(p Params, c distributorChannels, eng engine, kp <-chan rune, turnChan *channels.IntChannel, worldChan *HSliceChannel, wg *sync.WaitGroup, quit *channels.BoolChannel, paused bool) {
 	for {
 		key := <-kp
 		switch key {
@@ -196,10 +293,14 @@ func keypressParser(p Params, c distributorChannels, kp <-chan rune, turnChan *c
 			turn, _ := turnChan.Receive(true)
 			worldState := worldChan.Receive()
 			generatePGM(p, c, worldState.grid, turn)
-		case 'q':
-			// generate PGM image and terminate
+		case 'q', 'k':
+			// generate PGM image and terminate. 'q' leaves a broker running the turns without this controller,
+			// while 'k' shuts down the broker and its workers as well
 			if paused {
-				continue
+				// the distributor writes the image of the turn it was paused at once it has let go
+				quit.Send(key == 'k', true)
+				wg.Done()
+				return
 			}
 			turn, _ := turnChan.Receive(true)
 			worldState := worldChan.Receive()
@@ -208,10 +309,14 @@ func keypressParser(p Params, c distributorChannels, kp <-chan rune, turnChan *c
 			<-c.ioIdle
 			turn, _ = turnChan.Receive(true)
 			c.events <- StateChange{CompletedTurns: turn, NewState: Quitting}
-			quit.Send(true, true)
+			quit.Send(key == 'k', true)
 			return
 		case 'p':
 			// pause execution. If already paused, continue
+			// an engine computing turns on its own has to stop too
+			if pauser, ok := eng.(pauser); ok {
+				pauser.pause(!paused)
+			}
 			if paused {
 				paused = false
 				wg.Done()
@@ -230,24 +335,49 @@ func keypressParser(p Params, c distributorChannels, kp <-chan rune, turnChan *c
 
 // distributor divides the work between workers and interacts with other goroutines.
 func distributor(p Params, c distributorChannels, kp <-chan rune) {
-
-	// TODO: Give the filename to the io.channels.filename channel
-	c.ioCommand <- ioInput
-	// e.g., 64x64, 128x128 etc.
-	c.ioFilename <- (strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(p.ImageHeight))
+	// a controller attaching to a broker carries on with the run there, instead of loading the image
+	var remote *remoteEngine
+	if p.Attach {
+		var err error
+		remote, err = attachRemoteEngine(p, c)
+		util.Check(err)
+		p = remote.p
+	}
+	rule, err := selectRule(p)
+	util.Check(err)
+	t := newTopology(p)
 
 	// TODO: initialise the world
-	world := createNewSlice(p.ImageHeight, p.ImageWidth)
+	world := createNewSlice(worldRows(p), p.ImageWidth)
+	turn, paused := 0, false
+	if remote != nil {
+		world, turn, paused = remote.world(), remote.turn, remote.paused
+		state := Executing
+		if paused {
+			state = Paused
+		}
+		c.events <- StateChange{CompletedTurns: turn, NewState: state}
+	} else {
+		// TODO: Give the filename to the io.channels.filename channel
+		c.ioCommand <- ioInput
+		// e.g., 64x64, 128x128 etc.
+		c.ioFilename <- imageName(p)
+	}
 
 	// TODO: Populate blank world with world data from input
-	for i := 0; i < p.ImageHeight; i++ {
+	flips := newStateSender(p, c, turn, rule)
+	states := greyStates(rule.States())
+	for i := 0; i < worldRows(p); i++ {
 		for j := 0; j < p.ImageWidth; j++ {
-			world[i][j] = <-c.ioInput
-			if world[i][j] == 0xFF {
-				c.events <- CellFlipped{0, util.Cell{X: j, Y: i}}
+			if remote == nil {
+				world[i][j] = <-c.ioInput
+			}
+			if state := states[world[i][j]]; state != 0 {
+				flips.change(util.Cell{X: j, Y: i}, 0, state)
 			}
 		}
 	}
+	flips.send()
 
 	// start ticker to indicate alive cells
 	ticker := time.NewTicker(2 * time.Second)
@@ -257,74 +387,78 @@ func distributor(p Params, c distributorChannels, kp <-chan rune) {
 	kpStateUpdates := NewHSliceChannel(1)
 	quit := channels.NewBoolChannel()
 	var golLoop sync.WaitGroup
-	go keypressParser(p, c, kp, turnSender, kpStateUpdates, &golLoop, quit)
-
-	// TODO: Execute all turns of the Game of Life.
-	turn := 0
-	// creating channels
-	workerOutputChannel := NewHSliceChannel(p.Threads)
-	var waitgroup sync.WaitGroup
-	run := true
-
-	// pre-calculate work distribution
-	segment := p.ImageHeight / p.Threads
-	remainder := p.ImageHeight - (segment * p.Threads)
-	workSizes := []HorSlice{}
-	workerStartRow := 0
-	for i := 0; i < p.Threads; i++ {
-		work := HorSlice{grid: nil, startRow: workerStartRow, endRow: workerStartRow + segment}
-		if remainder > 0 {
-			work.endRow += 1
-			remainder--
-		}
-		workSizes = append(workSizes, work)
-		workerStartRow = work.endRow
+	var eng engine
+	if remote != nil {
+		eng = remote
+	} else {
+		eng, err = newEngine(p, world, rule, t, c)
+		util.Check(err)
 	}
+	if paused {
+		golLoop.Add(1)
+	}
+	go keypressParser(p, c, eng, kp, turnSender, kpStateUpdates, &golLoop, quit, paused)
 
-	for ; turn < p.Turns && run; turn++ {
+	// TODO: Execute all turns of the Game of Life.
+	quitting, kill := false, false
+	for turn < p.Turns {
 		turnSender.Send(turn, false)
-		kpStateUpdates.Send(HorSlice{grid: world, startRow: 0, endRow: 0}, false)
-		_, success := quit.Receive(false)
-		if success {
-			run = false
+		// only take a snapshot when the keypress parser has used the last one
+		if !kpStateUpdates.Full() {
+			kpStateUpdates.Send(HorSlice{grid: eng.world(), startRow: 0, endRow: 0}, false)
 		}
 		golLoop.Wait()
-		newWorld := createNewSlice(p.ImageHeight, p.ImageWidth)
+		// quitting while paused must not wait for a turn
+		if kill, quitting = quit.Receive(false); quitting {
+			break
+		}
 
-		// Initialise the worker threads
-		for tr := 0; tr < p.Threads; tr++ {
-			slice := workSizes[tr]
-			slice.grid = world
-			go worker(slice, p, workerOutputChannel, c, turn)
+		steps := eng.step(turn, p.Turns-turn)
+		if detacher, ok := eng.(detacher); ok && detacher.lost() != nil {
+			// a broker that cannot be reached may still have the run for another controller
+			quitting = true
+			break
 		}
-		for tr := 0; tr < p.Threads; tr++ {
-			newSlice := workerOutputChannel.Receive()
-			waitgroup.Add(1)
-			go func() {
-				for i := newSlice.startRow; i < newSlice.endRow; i++ {
-					for j := 0; j < p.ImageHeight; j++ {
-						newWorld[i][j] = newSlice.grid[i-newSlice.startRow][j]
-					}
-				}
-				waitgroup.Done()
-			}()
+		if steps == 0 {
+			// paused partway through the step
+			continue
 		}
-		waitgroup.Wait()
-		// updates world
-		world = newWorld
-		c.events <- TurnComplete{turn}
+		turn += steps
+		c.events <- TurnComplete{turn - 1}
 
 		// checking if ticker has ticked
-		checkTicker(ticker, world, turn+1, c)
+		checkTicker(ticker, eng, rule, turn, c)
 	}
 	// Generate a PGM image at turn 100
+	world = eng.world()
 	generatePGM(p, c, world, turn)
 
-	// Get a slice of the alive cells
-	aliveCells := getAliveCells(world)
+	// Get a slice of the alive cells and the state of every cell
+	aliveCells := eng.aliveCells()
+	finalStates := make([][]byte, len(world))
+	for i, row := range world {
+		finalStates[i] = make([]byte, len(row))
+		for j, grey := range row {
+			finalStates[i][j] = states[grey]
+		}
+	}
+
+	// the last count of each colour decides who survived longest
+	if colours, ok := rule.(ColourRule); ok {
+		c.events <- ColourCellsCount{CompletedTurns: turn, CellsCounts: countColours(world, colours)}
+	}
 
 	// TODO: Report the final state using FinalTurnCompleteEvent.
-	c.events <- FinalTurnComplete{CompletedTurns: p.Turns, Alive: aliveCells}
+	c.events <- FinalTurnComplete{CompletedTurns: p.Turns, Alive: aliveCells, States: finalStates}
+	if detacher, ok := eng.(detacher); ok && quitting {
+		if kill {
+			detacher.shutdown()
+		} else {
+			detacher.detach()
+		}
+	} else {
+		eng.stop()
+	}
 
 	// Make sure that the Io has finished any output before exiting.
 	c.ioCommand <- ioCheckIdle
@@ -0,0 +1,145 @@
+package gol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// ElementaryRule is one of Wolfram's 256 elementary cellular automata, such as rule 30 or rule 110, on a single row of cells.
+// Bit l*4+c*2+r of the rule number is the next state of a cell in state c with its left neighbour in state l
+// and its right neighbour in state r.
+// Running one gives a spacetime diagram instead of a world: the first row of the image, or a single alive cell
+// when Params.SingleCell is set, followed by every generation in the row below the last, so the output PGM
+// has Turns+1 rows. Only the first ImageHeight generations are shown by the GUI.
+type ElementaryRule struct {
+	Number byte
+}
+
+// ParseElementary parses a rulestring of W followed by a rule number of 0-255, such as "W110", as used by Golly
+func ParseElementary(rulestring string) (ElementaryRule, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(rulestring))
+	if !strings.HasPrefix(trimmed, "W") {
+		return ElementaryRule{}, fmt.Errorf("rule %q: expected W<number>", rulestring)
+	}
+	number, err := strconv.Atoi(trimmed[1:])
+	if err != nil || number < 0 || number > 255 {
+		return ElementaryRule{}, fmt.Errorf("rule %q: number %q is not in 0-255", rulestring, trimmed[1:])
+	}
+	return ElementaryRule{byte(number)}, nil
+}
+
+func (rule ElementaryRule) String() string {
+	return fmt.Sprintf("W%d", rule.Number)
+}
+
+func (rule ElementaryRule) States() int {
+	return 2
+}
+
+// Neighbourhood gets the cells before and after a cell in the row.
+// The row is run as a column, one cell in each row of the world, so they are the cells above and below it.
+func (rule ElementaryRule) Neighbourhood() []util.Cell {
+	return []util.Cell{{X: 0, Y: -1}, {X: 0, Y: 1}}
+}
+
+func (rule ElementaryRule) Next(state byte, neighbours []byte) byte {
+	return rule.Number >> (neighbours[0]<<2 | state<<1 | neighbours[1]) & 1
+}
+
+// elementaryEngine builds the spacetime diagram of an ElementaryRule.
+// The row is a stripEngine world turned on its side, one column with a cell in each row,
+// so that its cells are split between the workers like the rows of any other world.
+type elementaryEngine struct {
+	p       Params
+	c       distributorChannels
+	row     *stripEngine
+	diagram [][]byte
+	// the events of the turned world, which do not match the diagram, are thrown away
+	discard chan Event
+}
+
+func newElementaryEngine(p Params, world [][]byte, rule ElementaryRule, c distributorChannels) *elementaryEngine {
+	states := greyStates(2)
+	first := make([]byte, p.ImageWidth)
+	for j := range first {
+		if p.SingleCell && j == p.ImageWidth/2 || !p.SingleCell && states[world[0][j]] == 1 {
+			first[j] = 0xFF
+		}
+	}
+	// the whole image has been shown, so flip every cell that is not in the first generation
+	flips := newFlipSender(p, c, 0)
+	for i, row := range world {
+		for j, grey := range row {
+			if alive := states[grey] == 1; i > 0 && alive || i == 0 && alive != (first[j] == 0xFF) {
+				flips.flip(util.Cell{X: j, Y: i})
+			}
+		}
+	}
+	flips.send()
+
+	side := p
+	side.ImageWidth, side.ImageHeight = 1, p.ImageWidth
+	if side.Threads > side.ImageHeight {
+		side.Threads = side.ImageHeight
+	}
+	column := createNewSlice(side.ImageHeight, 1)
+	for j, grey := range first {
+		column[j][0] = grey
+	}
+	discard := make(chan Event)
+	go func() {
+		for range discard {
+		}
+	}()
+	sideChannels := c
+	sideChannels.events = discard
+	return &elementaryEngine{
+		p:       p,
+		c:       c,
+		row:     newStripEngine(side, column, rule, newTopology(side), sideChannels),
+		diagram: [][]byte{first},
+		discard: discard,
+	}
+}
+
+// compute the next generation and add it to the bottom of the diagram
+func (e *elementaryEngine) step(turn, turns int) int {
+	e.row.step(turn, 1)
+	next := make([]byte, e.p.ImageWidth)
+	for j, cell := range e.row.world() {
+		next[j] = cell[0]
+	}
+	e.diagram = append(e.diagram, next)
+
+	if y := len(e.diagram) - 1; y < e.p.ImageHeight {
+		flips := newFlipSender(e.p, e.c, turn)
+		for x, cell := range next {
+			if cell == 0xFF {
+				flips.flip(util.Cell{X: x, Y: y})
+			}
+		}
+		flips.send()
+	}
+	return 1
+}
+
+// get the spacetime diagram so far, which is only ever added to
+func (e *elementaryEngine) world() [][]byte {
+	return e.diagram
+}
+
+func (e *elementaryEngine) aliveCount() int {
+	return getAliveCellsCount(e.diagram)
+}
+
+func (e *elementaryEngine) aliveCells() []util.Cell {
+	return getAliveCells(e.diagram)
+}
+
+func (e *elementaryEngine) stop() {
+	e.row.stop()
+	close(e.discard)
+}
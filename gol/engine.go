@@ -0,0 +1,126 @@
+package gol
+
+import (
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Engine selects how the distributor computes each generation of the world.
+type Engine int
+
+const (
+	StripEngine    Engine = iota // one byte per cell, split into horizontal strips between workers
+	BitEngine                    // 64 cells per uint64, evolved a whole word at a time
+	HashLifeEngine               // memoised quadtree, jumping by powers of two
+	SparseEngine                 // unbounded plane, growing in chunks as alive cells spread
+)
+
+// names used by ParseEngine and the -engine flag
+var engineNames = map[string]Engine{
+	"strip":    StripEngine,
+	"bit":      BitEngine,
+	"hashlife": HashLifeEngine,
+	"sparse":   SparseEngine,
+}
+
+// ParseEngine gets the Engine for one of "strip", "bit", "hashlife" or "sparse".
+func ParseEngine(name string) (Engine, error) {
+	engine, ok := engineNames[name]
+	if !ok {
+		return StripEngine, fmt.Errorf("unknown engine %q", name)
+	}
+	return engine, nil
+}
+
+func (engine Engine) String() string {
+	switch engine {
+	case StripEngine:
+		return "Strip"
+	case BitEngine:
+		return "Bit"
+	case HashLifeEngine:
+		return "HashLife"
+	case SparseEngine:
+		return "Sparse"
+	default:
+		return "Incorrect Engine"
+	}
+}
+
+// engine holds the world and advances it through the generations
+type engine interface {
+	// compute at most turns generations, starting after turn, sending every cell that changes through
+	// a flipSender. Returns the number of generations computed, which is at least one unless the run
+	// is paused before any are.
+	step(turn, turns int) int
+	// get the current world as one byte per cell
+	world() [][]byte
+	// get the number of alive cells
+	aliveCount() int
+	// get the position of every alive cell
+	aliveCells() []util.Cell
+	// stop any goroutines kept between steps
+	stop()
+}
+
+// create the engine selected by the params, starting from the given world
+func newEngine(p Params, world [][]byte, rule Rule, t topology, c distributorChannels) (engine, error) {
+	if err := checkStochastic(p, rule); err != nil {
+		return nil, err
+	}
+	if err := checkVolume(p, rule); err != nil {
+		return nil, err
+	}
+	if err := checkKernel(p, rule); err != nil {
+		return nil, err
+	}
+	if p.Broker != "" {
+		return newRemoteEngine(p, world, rule, c)
+	}
+	if p.ImageDepth > 0 {
+		return newVolumeEngine(p, world, rule, c), nil
+	}
+	if elementary, ok := rule.(ElementaryRule); ok {
+		if p.Engine != StripEngine {
+			return nil, fmt.Errorf("the %v engine does not support 1D rules", p.Engine)
+		}
+		return newElementaryEngine(p, world, elementary, c), nil
+	}
+	if p.Engine == StripEngine {
+		return newStripEngine(p, world, rule, t, c), nil
+	}
+	// the other engines are built around counting alive neighbours
+	life, ok := rule.(LifeRule)
+	if !ok {
+		return nil, fmt.Errorf("the %v engine only supports B/S rules, not %v", p.Engine, rule)
+	}
+	switch p.Engine {
+	case BitEngine:
+		return newBitEngine(p, world, life, t, c), nil
+	case HashLifeEngine:
+		return newHashLifeEngine(p, world, life, c)
+	case SparseEngine:
+		return newSparseEngine(p, world, life, c)
+	default:
+		return nil, fmt.Errorf("unknown engine %v", p.Engine)
+	}
+}
+
+// divide the rows of the world into one strip per worker, spreading the remainder over the first strips
+func splitRows(height, threads int) []HorSlice {
+	segment := height / threads
+	remainder := height - (segment * threads)
+	workSizes := []HorSlice{}
+	workerStartRow := 0
+	for i := 0; i < threads; i++ {
+		work := HorSlice{grid: nil, startRow: workerStartRow, endRow: workerStartRow + segment}
+		if remainder > 0 {
+			work.endRow += 1
+			remainder--
+		}
+		workSizes = append(workSizes, work)
+		workerStartRow = work.endRow
+	}
+	return workSizes
+}
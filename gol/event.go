@@ -21,6 +21,15 @@ type AliveCellsCount struct { // implements Event
 	CellsCount     int
 }
 
+// ColourCellsCount is an Event notifying the user about the number of cells of each colour of a ColourRule,
+// such as Immigration or QuadLife. CellsCounts[c] is the number of cells in state c, so CellsCounts[0] is the dead cells
+// and the rest are the alive cells of each colour.
+// This Event is sent after every AliveCellsCount and once more before FinalTurnComplete.
+type ColourCellsCount struct { // implements Event
+	CompletedTurns int
+	CellsCounts    []int
+}
+
 // ImageOutputComplete is an Event notifying the user about the completion of output.
 // This Event should be sent every time an image has been saved.
 type ImageOutputComplete struct { // implements Event
@@ -52,6 +61,22 @@ type CellFlipped struct { // implements Event
 	Cell           util.Cell
 }
 
+// CellsFlipped is an Event notifying the GUI about a change of state of many cells at once.
+// It is sent instead of CellFlipped when Params.BatchFlips is set, at most once per worker each turn.
+type CellsFlipped struct { // implements Event
+	CompletedTurns int
+	Cells          []util.Cell
+}
+
+// CellsChanged is an Event notifying the GUI about cells changing to any state, with the new state of each cell.
+// It is sent instead of CellFlipped and CellsFlipped by the StripEngine when Params.Colour is set,
+// at most once per worker each turn.
+type CellsChanged struct { // implements Event
+	CompletedTurns int
+	Cells          []util.Cell
+	States         []byte
+}
+
 // TurnComplete is an Event notifying the GUI about turn completion.
 // SDL will render a frame when this event is sent.
 // All CellFlipped events must be sent *before* TurnComplete.
@@ -62,9 +87,12 @@ type TurnComplete struct { // implements Event
 // FinalTurnComplete is an Event notifying the testing framework about the new world state after execution finished.
 // The data included with this Event is used directly by the tests.
 // SDL closes the window when this Event is sent.
+// States holds the state of every cell, indexed [y][x], for rules with dying or other extra states.
+// For the SparseEngine it covers the same area as the output PGM.
 type FinalTurnComplete struct {
 	CompletedTurns int
 	Alive          []util.Cell
+	States         [][]byte
 }
 
 // String methods allow the different types of Events and States to be printed.
@@ -98,6 +126,14 @@ func (event AliveCellsCount) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event ColourCellsCount) String() string {
+	return fmt.Sprintf("Colour Cells %v", event.CellsCounts[1:])
+}
+
+func (event ColourCellsCount) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event ImageOutputComplete) String() string {
 	return fmt.Sprintf("File %v output complete", event.Filename)
 }
@@ -114,6 +150,22 @@ func (event CellFlipped) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event CellsFlipped) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event CellsFlipped) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event CellsChanged) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event CellsChanged) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event TurnComplete) String() string {
 	return fmt.Sprintf("")
 }
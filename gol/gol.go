@@ -6,6 +6,49 @@ type Params struct {
 	Threads     int
 	ImageWidth  int
 	ImageHeight int
+	Rule        string   // rulestring given to ParseRulestring, e.g. "B36/S23", "B2/S/C3" or "Wireworld". Defaults to ConwayRule
+	Boundary    Boundary // how the edges of the world are joined. Defaults to Torus
+	Shift       int      // horizontal shift of the top/bottom edges of a TwistedTorus
+	Engine      Engine   // how generations are computed. Defaults to StripEngine
+	Kernel      Kernel   // how the StripEngine computes each cell. Defaults to AutoKernel
+	BatchFlips  bool     // send a CellsFlipped event per worker each turn instead of a CellFlipped event per cell
+	// Colour reads and writes P6 PPM images instead of PGM, with each state in the colour given by StateColours,
+	// and makes the StripEngine send CellsChanged events so that the GUI can show every state
+	Colour bool
+	// Automaton is used instead of the Rule rulestring when set, e.g. Wireworld or a Rule from outside this package.
+	// Only the StripEngine supports rules other than LifeRule
+	Automaton Rule
+	// Stochastic makes births happen with BirthProbability and cells that survive die with DeathProbability,
+	// using a random number for each cell and turn made from Seed, so the result does not depend on Threads.
+	// Only the StripEngine supports it, and it computes every cell on every turn
+	Stochastic       bool
+	BirthProbability float64
+	DeathProbability float64
+	Seed             int64
+	// SingleCell starts an ElementaryRule from one alive cell in the middle of the row instead of the first row of the image
+	SingleCell bool
+	// ImageDepth makes a 3D world of that many layers for a BaysRule such as 4555, read and written as a directory
+	// of PGM slices. The layers come one after another in the rows of FinalTurnComplete.States and in the cells
+	// of events, so row y of layer z is row z*ImageHeight+y
+	ImageDepth int
+	// HashLifeNodes limits the nodes and results memoised by the HashLifeEngine, which are garbage collected
+	// when a jump reaches it. Defaults to 1 << 20
+	HashLifeNodes int
+	// Broker is the address of a broker, such as "127.0.0.1:8030", to run the turns on its workers instead of in
+	// this process. It needs the StripEngine and a 2D world. The broker runs ahead of the events, and a slow
+	// consumer gets the cells flipped over several turns at once, labelled with the last of them,
+	// though every turn still gets a TurnComplete event. Pressing 'q' leaves the run going on the broker,
+	// and 'k' shuts down the broker and its workers
+	Broker string
+	// Attach makes the controller attach to the run already on the Broker, which another controller left with 'q',
+	// instead of starting one from the image. The run keeps its own params, apart from its size which has to match
+	Attach bool
+	// Gather makes a broker send each worker its whole strip and gather it back every turn,
+	// instead of the workers exchanging the cells beyond the edges of their strips directly
+	Gather bool
+	// CheckpointTurns is how often a broker gathers the strips of its workers, to roll back to if one fails.
+	// Defaults to 100
+	CheckpointTurns int
 }
 
 // Run starts the processing of Game of Life. It should initialise channels and goroutines.
@@ -16,6 +59,7 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 	ioCommand := make(chan ioCommand)
 	ioIdle := make(chan bool)
 	filename := make(chan string)
+	size := make(chan imageSize)
 	output := make(chan uint8)
 	input := make(chan uint8)
 
@@ -23,6 +67,7 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 		command:  ioCommand,
 		idle:     ioIdle,
 		filename: filename,
+		size:     size,
 		output:   output,
 		input:    input,
 	}
@@ -33,6 +78,7 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 		ioCommand:  ioCommand,
 		ioIdle:     ioIdle,
 		ioFilename: filename,
+		ioSize:     size,
 		ioOutput:   output,
 		ioInput:    input,
 	}
@@ -0,0 +1,335 @@
+package gol
+
+import (
+	"errors"
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// default limit on the number of memoised nodes and results before the HashLife cache is collected
+const defaultHashLifeNodes = 1 << 20
+
+// a canonical square of 2^level x 2^level cells. Equal squares are always the same *hashNode.
+type hashNode struct {
+	level          uint
+	nw, ne, sw, se *hashNode
+	population     int
+}
+
+// key of an interned node, made from its (already canonical) quadrants
+type hashKey [4]*hashNode
+
+// key of a memoised result: the centre of node advanced 2^jump generations
+type resultKey struct {
+	node *hashNode
+	jump uint
+}
+
+// hashLifeEngine advances the world by powers of two using Gosper's memoised quadtree algorithm.
+// The torus is tiled into a periodic 2^k x 2^k square, which lets the quadtree step it as an infinite plane:
+// the result of stepping any tiling of the square is another tiling of the stepped square.
+// Only power-of-two sizes on a Torus are supported, and it runs on a single thread.
+type hashLifeEngine struct {
+	p       Params
+	rule    LifeRule
+	c       distributorChannels
+	k       uint      // the world is tiled into a 2^k x 2^k square
+	square  *hashNode // the current world, tiled into the square
+	grid    [][]byte  // the current world, one byte per cell
+	nodes   map[hashKey]*hashNode
+	results map[resultKey]*hashNode
+	dead    *hashNode
+	alive   *hashNode
+	limit   int
+	maxJump uint // largest power of two to step by, adapted to the cache limit
+	bounded bool // whether a jump gives up when the cache is full, see advance
+}
+
+// errCacheFull unwinds a jump that would memoise more than the limit of nodes and results
+var errCacheFull = errors.New("the hashlife cache is full")
+
+func newHashLifeEngine(p Params, world [][]byte, rule LifeRule, c distributorChannels) (*hashLifeEngine, error) {
+	if !isPowerOfTwo(p.ImageWidth) || !isPowerOfTwo(p.ImageHeight) {
+		return nil, fmt.Errorf("hashlife needs a power-of-two world size, not %vx%v", p.ImageWidth, p.ImageHeight)
+	}
+	if p.Boundary != Torus {
+		return nil, fmt.Errorf("hashlife only supports a torus, not a %v", p.Boundary)
+	}
+	e := &hashLifeEngine{
+		p:       p,
+		rule:    rule,
+		c:       c,
+		grid:    world,
+		nodes:   make(map[hashKey]*hashNode),
+		results: make(map[resultKey]*hashNode),
+		dead:    &hashNode{},
+		alive:   &hashNode{population: 1},
+		limit:   p.HashLifeNodes,
+	}
+	if e.limit <= 0 {
+		e.limit = defaultHashLifeNodes
+	}
+	for 1<<e.k < p.ImageWidth || 1<<e.k < p.ImageHeight {
+		e.k++
+	}
+	e.square = e.build(world, 0, 0, e.k)
+	return e, nil
+}
+
+func isPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+// get the canonical node with the given quadrants
+func (e *hashLifeEngine) join(nw, ne, sw, se *hashNode) *hashNode {
+	key := hashKey{nw, ne, sw, se}
+	if node, ok := e.nodes[key]; ok {
+		return node
+	}
+	e.reserve()
+	node := &hashNode{
+		level:      nw.level + 1,
+		nw:         nw,
+		ne:         ne,
+		sw:         sw,
+		se:         se,
+		population: nw.population + ne.population + sw.population + se.population,
+	}
+	e.nodes[key] = node
+	return node
+}
+
+// build the node of the given level whose top left corner is (x, y), tiling the world periodically
+func (e *hashLifeEngine) build(world [][]byte, x, y int, level uint) *hashNode {
+	if level == 0 {
+		if world[y%e.p.ImageHeight][x%e.p.ImageWidth] == 0xFF {
+			return e.alive
+		}
+		return e.dead
+	}
+	half := 1 << (level - 1)
+	return e.join(
+		e.build(world, x, y, level-1),
+		e.build(world, x+half, y, level-1),
+		e.build(world, x, y+half, level-1),
+		e.build(world, x+half, y+half, level-1),
+	)
+}
+
+// get the centre of a node, one level down
+func (e *hashLifeEngine) centre(n *hashNode) *hashNode {
+	return e.join(n.nw.se, n.ne.sw, n.sw.ne, n.se.nw)
+}
+
+// step the centre 2x2 of a 4x4 node by one generation
+func (e *hashLifeEngine) base(n *hashNode) *hashNode {
+	cells := [4][4]*hashNode{
+		{n.nw.nw, n.nw.ne, n.ne.nw, n.ne.ne},
+		{n.nw.sw, n.nw.se, n.ne.sw, n.ne.se},
+		{n.sw.nw, n.sw.ne, n.se.nw, n.se.ne},
+		{n.sw.sw, n.sw.se, n.se.sw, n.se.se},
+	}
+	next := func(row, column int) *hashNode {
+		neighbourCount := 0
+		for i := row - 1; i <= row+1; i++ {
+			for j := column - 1; j <= column+1; j++ {
+				if i != row || j != column {
+					neighbourCount += cells[i][j].population
+				}
+			}
+		}
+		if e.rule.next(byte(cells[row][column].population), neighbourCount) == 1 {
+			return e.alive
+		}
+		return e.dead
+	}
+	return e.join(next(1, 1), next(1, 2), next(2, 1), next(2, 2))
+}
+
+// get the centre of a node of level L, advanced by 2^jump generations where jump <= L-2
+func (e *hashLifeEngine) result(n *hashNode, jump uint) *hashNode {
+	key := resultKey{n, jump}
+	if result, ok := e.results[key]; ok {
+		return result
+	}
+	var result *hashNode
+	if n.population == 0 && !e.rule.Birth[0] {
+		result = n.nw
+	} else if n.level == 2 {
+		result = e.base(n)
+	} else {
+		// nine overlapping sub-squares, one level down
+		n00, n01, n02 := n.nw, e.join(n.nw.ne, n.ne.nw, n.nw.se, n.ne.sw), n.ne
+		n10, n11, n12 := e.join(n.nw.sw, n.nw.se, n.sw.nw, n.sw.ne), e.centre(n), e.join(n.ne.sw, n.ne.se, n.se.nw, n.se.ne)
+		n20, n21, n22 := n.sw, e.join(n.sw.ne, n.se.nw, n.sw.se, n.se.sw), n.se
+
+		// at full speed both halves advance, otherwise only the first does
+		first := jump
+		if jump == n.level-2 {
+			first = jump - 1
+		}
+		a00, a01, a02 := e.result(n00, first), e.result(n01, first), e.result(n02, first)
+		a10, a11, a12 := e.result(n10, first), e.result(n11, first), e.result(n12, first)
+		a20, a21, a22 := e.result(n20, first), e.result(n21, first), e.result(n22, first)
+
+		second := func(nw, ne, sw, se *hashNode) *hashNode {
+			quadrant := e.join(nw, ne, sw, se)
+			if jump == n.level-2 {
+				return e.result(quadrant, jump-1)
+			}
+			return e.centre(quadrant)
+		}
+		result = e.join(
+			second(a00, a01, a10, a11),
+			second(a01, a02, a11, a12),
+			second(a10, a11, a20, a21),
+			second(a11, a12, a21, a22),
+		)
+	}
+	e.reserve()
+	e.results[key] = result
+	return result
+}
+
+// make sure there is room in the cache for one more node or result, giving up on the jump if there is not
+func (e *hashLifeEngine) reserve() {
+	if e.bounded && len(e.nodes)+len(e.results) >= e.limit {
+		panic(errCacheFull)
+	}
+}
+
+// write the cells of a node with its top left corner at (x, y) into the grid, clipped to the world
+func (e *hashLifeEngine) flatten(grid [][]byte, n *hashNode, x, y int) {
+	if x >= e.p.ImageWidth || y >= e.p.ImageHeight || n.population == 0 {
+		return
+	}
+	if n.level == 0 {
+		grid[y][x] = 0xFF
+		return
+	}
+	half := 1 << (n.level - 1)
+	e.flatten(grid, n.nw, x, y)
+	e.flatten(grid, n.ne, x+half, y)
+	e.flatten(grid, n.sw, x, y+half)
+	e.flatten(grid, n.se, x+half, y+half)
+}
+
+// keep only the nodes reachable from the current world and forget every memoised result
+func (e *hashLifeEngine) collect() {
+	nodes := make(map[hashKey]*hashNode)
+	var mark func(n *hashNode)
+	mark = func(n *hashNode) {
+		if n.level == 0 {
+			return
+		}
+		key := hashKey{n.nw, n.ne, n.sw, n.se}
+		if _, ok := nodes[key]; ok {
+			return
+		}
+		nodes[key] = n
+		mark(n.nw)
+		mark(n.ne)
+		mark(n.sw)
+		mark(n.se)
+	}
+	mark(e.square)
+	e.nodes = nodes
+	e.results = make(map[resultKey]*hashNode)
+}
+
+// jump by the largest power of two that fits in the remaining turns and the cache, then send the changed cells.
+// When the cache fills up during a jump it is collected and the jump tried again, halving it each time it does not
+// fit in the collected cache. Only a single generation that needs more than the whole cache is let past the limit.
+func (e *hashLifeEngine) step(turn, turns int) int {
+	jump := e.maxJump
+	for 1<<jump > turns {
+		jump--
+	}
+	next, ok := e.advance(jump, true)
+	collected, split := false, false
+	for !ok {
+		if collected {
+			jump--
+			split = true
+		}
+		e.collect()
+		collected = true
+		next, ok = e.advance(jump, true)
+		if !ok && jump == 0 {
+			e.collect()
+			next, ok = e.advance(0, false)
+		}
+	}
+	e.square = next
+
+	grid := createNewSlice(e.p.ImageHeight, e.p.ImageWidth)
+	e.flatten(grid, e.square, 0, 0)
+	flips := newFlipSender(e.p, e.c, turn+1<<jump-1)
+	for i := range grid {
+		for j := range grid[i] {
+			if grid[i][j] != e.grid[i][j] {
+				flips.flip(util.Cell{X: j, Y: i})
+			}
+		}
+	}
+	flips.send()
+	e.grid = grid
+
+	// back off when a jump had to be split, otherwise try a bigger one next time
+	if split {
+		e.maxJump = jump
+	} else if !collected && e.maxJump < 62 {
+		e.maxJump++
+	}
+	if len(e.nodes)+len(e.results) > e.limit {
+		e.collect()
+	}
+	return 1 << jump
+}
+
+// jump the square by 2^jump generations, giving up with ok false if bounded and the cache fills up first
+func (e *hashLifeEngine) advance(jump uint, bounded bool) (next *hashNode, ok bool) {
+	e.bounded = bounded
+	defer func() {
+		e.bounded = false
+		if r := recover(); r != nil {
+			if r != errCacheFull {
+				panic(r)
+			}
+			next, ok = nil, false
+		}
+	}()
+	// tile the square until it is big enough for the jump; its top left 2^k square stays aligned with the world
+	level := e.k
+	if jump > level {
+		level = jump
+	}
+	level += 2
+	root := e.square
+	for root.level < level {
+		root = e.join(root, root, root, root)
+	}
+	next = e.result(root, jump)
+	for next.level > e.k {
+		next = next.nw
+	}
+	return next, true
+}
+
+func (e *hashLifeEngine) world() [][]byte {
+	return e.grid
+}
+
+func (e *hashLifeEngine) aliveCount() int {
+	// the square holds whole copies of the world
+	copies := (1 << (2 * e.k)) / (e.p.ImageWidth * e.p.ImageHeight)
+	return e.square.population / copies
+}
+
+func (e *hashLifeEngine) aliveCells() []util.Cell {
+	return getAliveCells(e.world())
+}
+
+// nothing runs between steps
+func (e *hashLifeEngine) stop() {}
@@ -0,0 +1,42 @@
+package gol
+
+import (
+	"fmt"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// HexRule is an outer-totalistic rule on a hexagonal grid, where each cell has 6 neighbours, such as "B2/S34H".
+// The grid is stored in the world as usual, with each row sheared half a cell left of the row above it,
+// so a cell's neighbours are the cells to its left and right, the two above it to the left and the two below it to the right.
+type HexRule struct {
+	LifeRule
+}
+
+// ParseHexRule parses a B/S rulestring ending with H, such as "B2/S34H", with neighbour counts of 0-6
+func ParseHexRule(rulestring string) (HexRule, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(rulestring))
+	if !strings.HasSuffix(trimmed, "H") {
+		return HexRule{}, fmt.Errorf("rule %q: expected B<digits>/S<digits>H", rulestring)
+	}
+	life, err := ParseRule(strings.TrimSuffix(trimmed, "H"))
+	if err != nil {
+		return HexRule{}, err
+	}
+	for n := 7; n <= 8; n++ {
+		if life.Birth[n] || life.Survival[n] {
+			return HexRule{}, fmt.Errorf("rule %q: count %d is more than the 6 neighbours of a hexagonal cell", rulestring, n)
+		}
+	}
+	return HexRule{life}, nil
+}
+
+// String gives the canonical B/S rulestring of the rule, ending with H
+func (rule HexRule) String() string {
+	return rule.LifeRule.String() + "H"
+}
+
+func (rule HexRule) Neighbourhood() []util.Cell {
+	return Hexagonal.Neighbourhood(1)
+}
@@ -1,6 +1,7 @@
 package gol
 
 import (
+	"image/color"
 	"io/ioutil"
 	"os"
 	"strconv"
@@ -14,14 +15,24 @@ type ioChannels struct {
 	idle    chan<- bool
 
 	filename <-chan string
+	size     <-chan imageSize
 	output   <-chan uint8
 	input    chan<- uint8
 }
 
+// imageSize is the width and height of an image to be written, which can differ from the params
+// when the world is unbounded.
+type imageSize struct {
+	width, height int
+}
+
 // ioState is the internal ioState of the io goroutine.
 type ioState struct {
 	params   Params
 	channels ioChannels
+	// the colour of each state when Params.Colour is set, with the grey levels the distributor uses for them
+	colours []color.RGBA
+	greys   []byte
 }
 
 // ioCommand allows requesting behaviour from the io (pgm) goroutine.
@@ -42,29 +53,49 @@ const (
 func (io *ioState) writePgmImage() {
 	_ = os.Mkdir("out", os.ModePerm)
 
-	// Request a filename from the distributor.
+	// Request a filename and the image size from the distributor.
+	filename := <-io.channels.filename
+	size := <-io.channels.size
+
+	io.writePgmFile("out/"+filename+".pgm", size)
+}
+
+// writePgmSlices receives the layers of a 3D world one after another and writes each to a pgm file
+// in a directory named after the image, e.g. out/64x64x16x100/0.pgm for the bottom layer.
+func (io *ioState) writePgmSlices() {
 	filename := <-io.channels.filename
+	size := <-io.channels.size
 
-	file, ioError := os.Create("out/" + filename + ".pgm")
+	ioError := os.MkdirAll("out/"+filename, os.ModePerm)
+	util.Check(ioError)
+	layer := imageSize{width: size.width, height: size.height / io.params.ImageDepth}
+	for z := 0; z < io.params.ImageDepth; z++ {
+		io.writePgmFile("out/"+filename+"/"+strconv.Itoa(z)+".pgm", layer)
+	}
+}
+
+// writePgmFile receives the bytes of an image of the given size and writes them to a pgm file.
+func (io *ioState) writePgmFile(path string, size imageSize) {
+	file, ioError := os.Create(path)
 	util.Check(ioError)
 	defer file.Close()
 
 	_, _ = file.WriteString("P5\n")
 	//_, _ = file.WriteString("# PGM file writer by pnmmodules (https://github.com/owainkenwayucl/pnmmodules).\n")
-	_, _ = file.WriteString(strconv.Itoa(io.params.ImageWidth))
+	_, _ = file.WriteString(strconv.Itoa(size.width))
 	_, _ = file.WriteString(" ")
-	_, _ = file.WriteString(strconv.Itoa(io.params.ImageHeight))
+	_, _ = file.WriteString(strconv.Itoa(size.height))
 	_, _ = file.WriteString("\n")
 	_, _ = file.WriteString(strconv.Itoa(255))
 	_, _ = file.WriteString("\n")
 
-	world := make([][]byte, io.params.ImageHeight)
+	world := make([][]byte, size.height)
 	for i := range world {
-		world[i] = make([]byte, io.params.ImageWidth)
+		world[i] = make([]byte, size.width)
 	}
 
-	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
+	for y := 0; y < size.height; y++ {
+		for x := 0; x < size.width; x++ {
 			val := <-io.channels.output
 			//if val != 0 {
 			//	fmt.Println(x, y)
@@ -73,8 +104,8 @@ func (io *ioState) writePgmImage() {
 		}
 	}
 
-	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
+	for y := 0; y < size.height; y++ {
+		for x := 0; x < size.width; x++ {
 			_, ioError = file.Write([]byte{world[y][x]})
 			util.Check(ioError)
 		}
@@ -92,7 +123,22 @@ func (io *ioState) readPgmImage() {
 	// Request a filename from the distributor.
 	filename := <-io.channels.filename
 
-	data, ioError := ioutil.ReadFile("images/" + filename + ".pgm")
+	io.readPgmFile("images/" + filename + ".pgm")
+}
+
+// readPgmSlices opens the pgm file of each layer of a 3D world, in a directory named after the image,
+// e.g. images/64x64x16/0.pgm for the bottom layer, and sends their data one layer after another.
+func (io *ioState) readPgmSlices() {
+	filename := <-io.channels.filename
+
+	for z := 0; z < io.params.ImageDepth; z++ {
+		io.readPgmFile("images/" + filename + "/" + strconv.Itoa(z) + ".pgm")
+	}
+}
+
+// readPgmFile opens a pgm file the size of the params and sends its data as an array of bytes.
+func (io *ioState) readPgmFile(path string) {
+	data, ioError := ioutil.ReadFile(path)
 	util.Check(ioError)
 
 	fields := strings.Fields(string(data))
@@ -116,7 +162,8 @@ func (io *ioState) readPgmImage() {
 		panic("Incorrect maxval/bit depth")
 	}
 
-	image := []byte(fields[4])
+	// the data may hold whitespace bytes for some grey levels, so it is the last width*height bytes of the file
+	image := data[len(data)-width*height:]
 
 	for _, b := range image {
 		io.channels.input <- b
@@ -125,12 +172,85 @@ func (io *ioState) readPgmImage() {
 	// fmt.Println("File", filename, "input done!")
 }
 
+// writePpmImage receives the grey level of each cell and writes the colour of its state to a P6 ppm file.
+func (io *ioState) writePpmImage() {
+	_ = os.Mkdir("out", os.ModePerm)
+
+	filename := <-io.channels.filename
+	size := <-io.channels.size
+
+	states := greyStates(len(io.colours))
+	pixels := make([]byte, 0, 3*size.width*size.height)
+	for i := 0; i < size.width*size.height; i++ {
+		colour := io.colours[states[<-io.channels.output]]
+		pixels = append(pixels, colour.R, colour.G, colour.B)
+	}
+
+	header := "P6\n" + strconv.Itoa(size.width) + " " + strconv.Itoa(size.height) + "\n255\n"
+	ioError := ioutil.WriteFile("out/"+filename+".ppm", append([]byte(header), pixels...), 0644)
+	util.Check(ioError)
+}
+
+// readPpmImage opens a P6 ppm file and sends the grey level of the state with the nearest colour to each pixel.
+func (io *ioState) readPpmImage() {
+	filename := <-io.channels.filename
+
+	data, ioError := ioutil.ReadFile("images/" + filename + ".ppm")
+	util.Check(ioError)
+
+	fields := strings.Fields(string(data))
+
+	if fields[0] != "P6" {
+		panic("Not a ppm file")
+	}
+
+	width, _ := strconv.Atoi(fields[1])
+	if width != io.params.ImageWidth {
+		panic("Incorrect width")
+	}
+
+	height, _ := strconv.Atoi(fields[2])
+	if height != io.params.ImageHeight {
+		panic("Incorrect height")
+	}
+
+	maxval, _ := strconv.Atoi(fields[3])
+	if maxval != 255 {
+		panic("Incorrect maxval/bit depth")
+	}
+
+	// as with pgm files, the image is the last 3*width*height bytes of the file
+	image := data[len(data)-3*width*height:]
+
+	for i := 0; i < len(image); i += 3 {
+		io.channels.input <- io.greys[nearestColour(io.colours, image[i], image[i+1], image[i+2])]
+	}
+}
+
+// get the state whose colour is closest to a pixel
+func nearestColour(colours []color.RGBA, r, g, b byte) int {
+	nearest, state := -1, 0
+	for s, colour := range colours {
+		dr, dg, db := int(r)-int(colour.R), int(g)-int(colour.G), int(b)-int(colour.B)
+		if distance := dr*dr + dg*dg + db*db; nearest < 0 || distance < nearest {
+			nearest, state = distance, s
+		}
+	}
+	return state
+}
+
 // startIo should be the entrypoint of the io goroutine.
 func startIo(p Params, c ioChannels) {
 	io := ioState{
 		params:   p,
 		channels: c,
 	}
+	if p.Colour {
+		var err error
+		io.colours, err = StateColours(p)
+		util.Check(err)
+		io.greys = stateGreys(len(io.colours))
+	}
 
 	for {
 		select {
@@ -138,9 +258,21 @@ func startIo(p Params, c ioChannels) {
 		case command := <-io.channels.command:
 			switch command {
 			case ioInput:
-				io.readPgmImage()
+				if p.ImageDepth > 0 {
+					io.readPgmSlices()
+				} else if p.Colour {
+					io.readPpmImage()
+				} else {
+					io.readPgmImage()
+				}
 			case ioOutput:
-				io.writePgmImage()
+				if p.ImageDepth > 0 {
+					io.writePgmSlices()
+				} else if p.Colour {
+					io.writePpmImage()
+				} else {
+					io.writePgmImage()
+				}
 			case ioCheckIdle:
 				io.channels.idle <- true
 			}
@@ -0,0 +1,282 @@
+package gol
+
+import (
+	"fmt"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// IsotropicRule is an isotropic non-totalistic rule in Hensel notation, such as "B2-a/S12" (Just Friends).
+// Whether a cell is born or survives depends on how its alive neighbours are arranged as well as how many there are,
+// but arrangements that are rotations or reflections of each other always give the same result.
+type IsotropicRule struct {
+	// the next state of a cell for each 3x3 neighbourhood, see neighbourhoodIndex
+	table [512]byte
+}
+
+// a class of arrangements of alive neighbours, given by the number of them and a letter in Hensel notation.
+// Counts of 0 and 8 only have one arrangement and no letter.
+type henselClass struct {
+	count  int
+	letter byte
+}
+
+// the letters used with 0-4 alive neighbours, in the order they are written.
+// A count of n above 4 uses the same letters as 8-n, each arrangement being the opposite of the one for 8-n.
+var henselLetters = [5]string{"", "ce", "ceaikn", "ceaiknjqry", "ceaiknjqrytwz"}
+
+// an arrangement of neighbours for each letter, as used by Golly
+var henselArrangements = [5][]int{
+	{0},
+	{1, 2},
+	{5, 10, 3, 40, 33, 68},
+	{69, 42, 11, 7, 98, 13, 14, 70, 41, 97},
+	{325, 170, 15, 45, 99, 71, 106, 102, 43, 101, 105, 78, 108},
+}
+
+// the class of each arrangement of neighbours, indexed as in neighbourhoodIndex with the cell itself dead
+var henselClasses = classifyArrangements()
+
+// the bit of a neighbourhood index for the cell in row 1+dy, column 1+dx of a 3x3 neighbourhood, the cell itself being bit 4
+func neighbourhoodBit(dx, dy int) uint {
+	return uint((dy+1)*3 + dx + 1)
+}
+
+// get the letters used with a number of alive neighbours
+func lettersFor(count int) string {
+	if count > 4 {
+		return henselLetters[8-count]
+	}
+	return henselLetters[count]
+}
+
+// get an arrangement of the class, as a neighbourhood index with the cell itself dead
+func (class henselClass) arrangement() int {
+	count, letter := class.count, class.letter
+	opposite := count > 4
+	if opposite {
+		count = 8 - count
+	}
+	arrangement := henselArrangements[count][0]
+	if letter != 0 {
+		arrangement = henselArrangements[count][strings.IndexByte(henselLetters[count], letter)]
+	}
+	if opposite {
+		arrangement ^= 511 &^ (1 << 4)
+	}
+	return arrangement
+}
+
+// work out the class of every arrangement by rotating and reflecting one arrangement of each class
+func classifyArrangements() [512]henselClass {
+	var classes [512]henselClass
+	for count := 0; count <= 8; count++ {
+		letters := lettersFor(count)
+		if letters == "" {
+			letters = "\x00"
+		}
+		for k := range letters {
+			class := henselClass{count, letters[k]}
+			cells := class.arrangement()
+			for rotation := 0; rotation < 4; rotation++ {
+				for _, reflect := range []bool{false, true} {
+					index := 0
+					for bit := uint(0); bit < 9; bit++ {
+						if cells&(1<<bit) == 0 {
+							continue
+						}
+						x, y := int(bit%3)-1, int(bit/3)-1
+						for r := 0; r < rotation; r++ {
+							x, y = -y, x
+						}
+						if reflect {
+							x = -x
+						}
+						index |= 1 << neighbourhoodBit(x, y)
+					}
+					classes[index] = class
+				}
+			}
+		}
+	}
+	return classes
+}
+
+// ParseIsotropic parses a B/S rulestring in Hensel notation, such as "B2-a/S12" or "B3/S2-i34q" (tlife).
+// Each count of alive neighbours may be followed by letters for the arrangements it applies to,
+// or by a minus and the letters of the arrangements it does not apply to. A count on its own applies to every arrangement.
+func ParseIsotropic(rulestring string) (IsotropicRule, error) {
+	var rule IsotropicRule
+	parts := strings.Split(strings.TrimSpace(rulestring), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return rule, fmt.Errorf("rule %q: expected B<counts>/S<counts>", rulestring)
+	}
+	birth, survival := parts[0], parts[1]
+	switch strings.ToUpper(birth[:1] + survival[:1]) {
+	case "BS":
+	case "SB":
+		birth, survival = survival, birth
+	default:
+		return rule, fmt.Errorf("rule %q: expected B<counts>/S<counts>", rulestring)
+	}
+	born, err := parseHensel(strings.ToLower(birth[1:]))
+	if err != nil {
+		return rule, fmt.Errorf("rule %q: birth %v", rulestring, err)
+	}
+	survives, err := parseHensel(strings.ToLower(survival[1:]))
+	if err != nil {
+		return rule, fmt.Errorf("rule %q: survival %v", rulestring, err)
+	}
+	for index := range rule.table {
+		class := henselClasses[index&^(1<<4)]
+		if index&(1<<4) == 0 && born[class] || index&(1<<4) != 0 && survives[class] {
+			rule.table[index] = 1
+		}
+	}
+	return rule, nil
+}
+
+// get the classes of arrangements listed by the counts and letters of one half of a rulestring
+func parseHensel(counts string) (map[henselClass]bool, error) {
+	classes := make(map[henselClass]bool)
+	var seen [9]bool
+	for counts != "" {
+		if counts[0] < '0' || counts[0] > '8' {
+			return nil, fmt.Errorf("count %q is not in 0-8", counts[0])
+		}
+		count := int(counts[0] - '0')
+		if seen[count] {
+			return nil, fmt.Errorf("count %d is repeated", count)
+		}
+		seen[count] = true
+		counts = counts[1:]
+		minus := strings.HasPrefix(counts, "-")
+		if minus {
+			counts = counts[1:]
+		}
+		end := strings.IndexAny(counts, "012345678")
+		if end < 0 {
+			end = len(counts)
+		}
+		letters := counts[:end]
+		counts = counts[end:]
+
+		valid := lettersFor(count)
+		if minus && letters == "" {
+			return nil, fmt.Errorf("count %d: expected letters after -", count)
+		}
+		for k := range letters {
+			if strings.IndexByte(valid, letters[k]) < 0 {
+				return nil, fmt.Errorf("count %d: letter %q is not one of %q", count, letters[k], valid)
+			}
+			if strings.IndexByte(letters[k+1:], letters[k]) >= 0 {
+				return nil, fmt.Errorf("count %d: letter %q is repeated", count, letters[k])
+			}
+		}
+		if valid == "" {
+			classes[henselClass{count, 0}] = true
+		}
+		for k := range valid {
+			if letters == "" || strings.IndexByte(letters, valid[k]) >= 0 != minus {
+				classes[henselClass{count, valid[k]}] = true
+			}
+		}
+	}
+	return classes, nil
+}
+
+// String gives the rulestring of the rule in Hensel notation, listing whichever of the letters a count applies to
+// or does not apply to is shorter
+func (rule IsotropicRule) String() string {
+	var b strings.Builder
+	for _, half := range []struct {
+		prefix string
+		cell   int
+	}{{"B", 0}, {"/S", 1 << 4}} {
+		b.WriteString(half.prefix)
+		for count := 0; count <= 8; count++ {
+			letters := lettersFor(count)
+			if letters == "" {
+				if rule.table[henselClass{count, 0}.arrangement()|half.cell] == 1 {
+					fmt.Fprint(&b, count)
+				}
+				continue
+			}
+			var included, excluded string
+			for k := range letters {
+				if rule.table[henselClass{count, letters[k]}.arrangement()|half.cell] == 1 {
+					included += letters[k : k+1]
+				} else {
+					excluded += letters[k : k+1]
+				}
+			}
+			switch {
+			case excluded == "":
+				fmt.Fprint(&b, count)
+			case included == "":
+			case len(excluded) < len(included):
+				fmt.Fprintf(&b, "%d-%v", count, excluded)
+			default:
+				fmt.Fprintf(&b, "%d%v", count, included)
+			}
+		}
+	}
+	return b.String()
+}
+
+func (rule IsotropicRule) States() int {
+	return 2
+}
+
+func (rule IsotropicRule) Neighbourhood() []util.Cell {
+	return MooreNeighbourhood(1)
+}
+
+func (rule IsotropicRule) Next(state byte, neighbours []byte) byte {
+	index := int(state) << 4
+	for k, neighbour := range neighbours {
+		// neighbours come row by row, skipping the cell itself at bit 4
+		bit := uint(k)
+		if k >= 4 {
+			bit++
+		}
+		index |= int(neighbour) << bit
+	}
+	return rule.table[index]
+}
+
+func (rule IsotropicRule) lookup() *[512]byte {
+	return &rule.table
+}
+
+// tableRule is a Rule that looks up the next state of a cell from its 3x3 neighbourhood, with only states 0 and 1.
+// Workers build the index of each cell straight from their buffers instead of collecting its neighbours.
+type tableRule interface {
+	Rule
+	// lookup gets the next state for each neighbourhood index, see neighbourhoodIndex
+	lookup() *[512]byte
+}
+
+// get the index of the 3x3 neighbourhood around row i, column j of a grid of states 0 and 1,
+// with bit (dy+1)*3+dx+1 set if the cell dx columns and dy rows away is alive
+func neighbourhoodIndex(grid [][]byte, i, j int) int {
+	index := 0
+	for dy := -1; dy <= 1; dy++ {
+		row := grid[i+dy]
+		for dx := -1; dx <= 1; dx++ {
+			index |= int(row[j+dx]) << neighbourhoodBit(dx, dy)
+		}
+	}
+	return index
+}
+
+// get the IsotropicRule that does the same as a B/S rule, so that workers can look it up in a table
+func (rule LifeRule) isotropic() IsotropicRule {
+	var isotropic IsotropicRule
+	for index := range isotropic.table {
+		count := henselClasses[index&^(1<<4)].count
+		isotropic.table[index] = rule.next(byte(index>>4&1), count)
+	}
+	return isotropic
+}
@@ -0,0 +1,269 @@
+package gol
+
+import (
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Kernel selects how the workers of the StripEngine compute the next state of each cell.
+type Kernel int
+
+const (
+	AutoKernel      Kernel = iota // the fastest kernel the rule supports: table, then sum, then neighbour
+	NeighbourKernel               // collect the neighbours of each cell and pass them to Rule.Next, for any rule
+	SumKernel                     // slide along each row keeping running sums of the alive cells in each column, for counting rules
+	TableKernel                   // slide along each row packing the 3x3 neighbourhood into bits to look up, for B/S rules
+)
+
+// names used by ParseKernel and the -kernel flag
+var kernelNames = map[string]Kernel{
+	"auto":      AutoKernel,
+	"neighbour": NeighbourKernel,
+	"sum":       SumKernel,
+	"table":     TableKernel,
+}
+
+// ParseKernel gets the Kernel for one of "auto", "neighbour", "sum" or "table".
+func ParseKernel(name string) (Kernel, error) {
+	kernel, ok := kernelNames[name]
+	if !ok {
+		return AutoKernel, fmt.Errorf("unknown kernel %q", name)
+	}
+	return kernel, nil
+}
+
+func (kernel Kernel) String() string {
+	switch kernel {
+	case AutoKernel:
+		return "Auto"
+	case NeighbourKernel:
+		return "Neighbour"
+	case SumKernel:
+		return "Sum"
+	case TableKernel:
+		return "Table"
+	default:
+		return "Incorrect Kernel"
+	}
+}
+
+// check the kernel selected by the params can run the rule
+func checkKernel(p Params, rule Rule) error {
+	if p.Kernel == AutoKernel {
+		return nil
+	}
+	if p.Engine != StripEngine || p.ImageDepth > 0 {
+		return fmt.Errorf("the %v kernel is only used by the strip engine in 2D", p.Kernel)
+	}
+	_, err := resolveKernel(p.Kernel, rule)
+	return err
+}
+
+// get the kernel used for a rule, which for AutoKernel is the fastest one it supports
+func resolveKernel(kernel Kernel, rule Rule) (Kernel, error) {
+	_, table := ruleTable(rule)
+	_, sums := ruleSpans(rule)
+	switch kernel {
+	case AutoKernel:
+		if table {
+			return TableKernel, nil
+		}
+		if sums {
+			return SumKernel, nil
+		}
+		return NeighbourKernel, nil
+	case NeighbourKernel:
+	case SumKernel:
+		if !sums {
+			return kernel, fmt.Errorf("rule %v does not count its neighbours, so cannot use the %v kernel", rule, kernel)
+		}
+	case TableKernel:
+		if !table {
+			return kernel, fmt.Errorf("rule %v does not have a 3x3 lookup table, so cannot use the %v kernel", rule, kernel)
+		}
+	default:
+		return kernel, fmt.Errorf("unknown kernel %v", kernel)
+	}
+	return kernel, nil
+}
+
+// get the table of the next state for every 3x3 neighbourhood of a rule with only states 0 and 1
+func ruleTable(rule Rule) (*[512]byte, bool) {
+	switch r := rule.(type) {
+	case tableRule:
+		return r.lookup(), true
+	case LifeRule:
+		return r.isotropic().lookup(), true
+	}
+	return nil, false
+}
+
+// get a rule that only depends on the number of alive cells in the spans of its neighbourhood
+func ruleSpans(rule Rule) (spanRule, bool) {
+	switch r := rule.(type) {
+	case spanRule:
+		return r, true
+	case LifeRule:
+		return lifeSpans{r, Moore}, true
+	case HexRule:
+		return lifeSpans{r.LifeRule, Hexagonal}, true
+	}
+	return nil, false
+}
+
+// lifeSpans counts the neighbours of a B/S rule on a square or hexagonal grid with running sums
+type lifeSpans struct {
+	LifeRule
+	shape Shape
+}
+
+func (rule lifeSpans) spans() []span {
+	return rule.shape.spans(1)
+}
+
+func (rule lifeSpans) nextCount(state byte, count int) byte {
+	// the count includes the cell itself
+	return rule.next(state, count-int(state))
+}
+
+// get the table of a hexagonal rule, making it a tableRule.
+// It counts every neighbour in the 3x3 neighbourhood but the north east and south west.
+func (rule HexRule) lookup() *[512]byte {
+	var table [512]byte
+	mask := 0
+	for _, offset := range rule.Neighbourhood() {
+		mask |= 1 << neighbourhoodBit(offset.X, offset.Y)
+	}
+	for index := range table {
+		count := 0
+		for neighbours := index & mask; neighbours != 0; neighbours &= neighbours - 1 {
+			count++
+		}
+		table[index] = rule.next(byte(index>>4&1), count)
+	}
+	return &table
+}
+
+// rowKernel computes the next state of a run of cells along a row of a worker's strip, into its next buffer
+type rowKernel interface {
+	// begin a turn, before any rows are computed
+	begin(w *worker)
+	// compute the cells in columns start to end of row bi of the buffers
+	row(w *worker, bi, start, end int)
+}
+
+// make the kernel for a worker, which must have been checked with resolveKernel
+func newRowKernel(kernel Kernel, rule Rule) rowKernel {
+	kernel, _ = resolveKernel(kernel, rule)
+	switch kernel {
+	case TableKernel:
+		table, _ := ruleTable(rule)
+		return &tableKernel{table: table}
+	case SumKernel:
+		counter, _ := ruleSpans(rule)
+		k := &sumKernel{rule: counter, spans: counter.spans(), uniform: true}
+		for _, s := range k.spans {
+			k.uniform = k.uniform && s == k.spans[0]
+		}
+		return k
+	}
+	return &neighbourKernel{rule: rule, neighbourhood: rule.Neighbourhood(), neighbours: make([]byte, len(rule.Neighbourhood()))}
+}
+
+// neighbourKernel gives each cell and its neighbours to Rule.Next
+type neighbourKernel struct {
+	rule          Rule
+	neighbourhood []util.Cell
+	neighbours    []byte
+}
+
+func (k *neighbourKernel) begin(w *worker) {}
+
+func (k *neighbourKernel) row(w *worker, bi, start, end int) {
+	for bj := start + w.radius; bj < end+w.radius; bj++ {
+		for n, offset := range k.neighbourhood {
+			k.neighbours[n] = w.cur[bi+offset.Y][bj+offset.X]
+		}
+		w.next[bi][bj] = k.rule.Next(w.cur[bi][bj], k.neighbours)
+	}
+}
+
+// sumKernel counts the alive cells in the neighbourhood of each cell with running sums.
+// When every row of the neighbourhood has the same span, as with the Moore neighbourhood, it sums each column of the rows
+// and slides along the row, adding the column entering the neighbourhood and taking away the one leaving it.
+// Otherwise it keeps the running sums along each row of the strip, see worker.sumRows.
+type sumKernel struct {
+	rule    spanRule
+	spans   []span
+	uniform bool
+	columns []int
+}
+
+func (k *sumKernel) begin(w *worker) {
+	if !k.uniform {
+		w.sumRows()
+	}
+}
+
+func (k *sumKernel) row(w *worker, bi, start, end int) {
+	rows := len(k.spans) / 2
+	if !k.uniform {
+		for bj := start + w.radius; bj < end+w.radius; bj++ {
+			count := 0
+			for n, s := range k.spans {
+				sums := w.sums[bi-rows+n]
+				count += sums[bj+s.to+1] - sums[bj+s.from]
+			}
+			w.next[bi][bj] = k.rule.nextCount(w.cur[bi][bj], count)
+		}
+		return
+	}
+
+	// the alive cells in each column the neighbourhoods of the run cover
+	s := k.spans[0]
+	first := start + w.radius + s.from
+	k.columns = k.columns[:0]
+	for bj := first; bj < end+w.radius+s.to; bj++ {
+		sum := 0
+		for bk := bi - rows; bk <= bi+rows; bk++ {
+			if w.cur[bk][bj] == 1 {
+				sum++
+			}
+		}
+		k.columns = append(k.columns, sum)
+	}
+	width := s.to - s.from + 1
+	count := 0
+	for _, sum := range k.columns[:width] {
+		count += sum
+	}
+	for bj := start + w.radius; bj < end+w.radius; bj++ {
+		w.next[bi][bj] = k.rule.nextCount(w.cur[bi][bj], count)
+		if left := bj + s.from - first; left+width < len(k.columns) {
+			count += k.columns[left+width] - k.columns[left]
+		}
+	}
+}
+
+// tableKernel looks up the next state of each cell from its 3x3 neighbourhood, see neighbourhoodIndex.
+// Sliding one cell along the row shifts the columns of the index down a bit and adds the column entering it.
+type tableKernel struct {
+	table *[512]byte
+}
+
+func (k *tableKernel) begin(w *worker) {}
+
+func (k *tableKernel) row(w *worker, bi, start, end int) {
+	above, row, below := w.cur[bi-1], w.cur[bi], w.cur[bi+1]
+	// the bits of column bj at dx = -1, moved left by dx+1 for the other columns
+	column := func(bj int) int {
+		return int(above[bj]) | int(row[bj])<<3 | int(below[bj])<<6
+	}
+	bj := start + w.radius
+	index := column(bj-1)<<1 | column(bj)<<2
+	for ; bj < end+w.radius; bj++ {
+		index = index>>1&0xDB | column(bj+1)<<2
+		w.next[bi][bj] = k.table[index]
+	}
+}
@@ -0,0 +1,54 @@
+package gol
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// BenchmarkKernels compares the kernels each rule supports computing every row of one worker's buffer,
+// reporting cells computed per second, so that the fastest can be picked with -kernel.
+// The buffer is filled at random once and the same turn is computed each time, leaving out the halo exchange.
+func BenchmarkKernels(b *testing.B) {
+	rules := []struct {
+		rule      string
+		imageSize int
+		kernels   []Kernel
+	}{
+		{"B3/S23", 512, []Kernel{NeighbourKernel, SumKernel, TableKernel}},
+		{"B2/S34H", 512, []Kernel{NeighbourKernel, SumKernel, TableKernel}},
+		{"B2-a/S12", 512, []Kernel{NeighbourKernel, TableKernel}},
+		{"R5,C0,M1,S34..58,B34..45,NM", 256, []Kernel{NeighbourKernel, SumKernel}},
+		{"R3,C0,M0,S4..9,B5..8,NN", 256, []Kernel{NeighbourKernel, SumKernel}},
+	}
+
+	for _, r := range rules {
+		rule, err := ParseRulestring(r.rule)
+		if err != nil {
+			b.Fatal(err)
+		}
+		radius := neighbourhoodRadius(rule.Neighbourhood())
+		w := newWorker(0, HorSlice{startRow: 0, endRow: r.imageSize}, radius, r.imageSize, 1, nil)
+		random := rand.New(rand.NewSource(42))
+		for _, row := range w.cur {
+			for j := range row {
+				row[j] = byte(random.Intn(2))
+			}
+		}
+		for _, kernel := range r.kernels {
+			name := fmt.Sprintf("rule=%v_kernel=%v_size=%dx%d_", r.rule, kernel, r.imageSize, r.imageSize)
+			b.Run(name, func(b *testing.B) {
+				k := newRowKernel(kernel, rule)
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					k.begin(w)
+					for bi := radius; bi < r.imageSize+radius; bi++ {
+						k.row(w, bi, 0, r.imageSize)
+					}
+				}
+				cells := float64(b.N) * float64(r.imageSize*r.imageSize)
+				b.ReportMetric(cells/b.Elapsed().Seconds(), "cells/s")
+			})
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package gol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Shape of a neighbourhood with a radius
+type Shape int
+
+const (
+	Moore      Shape = iota // every cell at most radius rows and radius columns away
+	VonNeumann              // every cell at most radius steps away along rows and columns
+	Circular                // every cell whose centre is less than radius+0.5 away, as in Golly
+	Hexagonal               // every cell at most radius steps away on a hexagonal grid, as in Golly
+)
+
+// letters used for each shape in Larger than Life rulestrings
+var shapeLetters = map[string]Shape{
+	"M": Moore,
+	"N": VonNeumann,
+	"C": Circular,
+	"H": Hexagonal,
+}
+
+func (shape Shape) String() string {
+	switch shape {
+	case Moore:
+		return "Moore"
+	case VonNeumann:
+		return "von Neumann"
+	case Circular:
+		return "Circular"
+	case Hexagonal:
+		return "Hexagonal"
+	default:
+		return "Incorrect Shape"
+	}
+}
+
+// the columns of a row in a neighbourhood, relative to the cell
+type span struct {
+	from, to int
+}
+
+// get the columns of the neighbourhood on each row from radius rows above a cell to radius rows below.
+// A hexagonal grid is stored with each row sheared half a cell left of the one above,
+// so the neighbours of a cell are its Moore neighbours apart from the north east and south west corners.
+func (shape Shape) spans(radius int) []span {
+	spans := make([]span, 2*radius+1)
+	for dy := -radius; dy <= radius; dy++ {
+		s := span{-radius, radius}
+		switch shape {
+		case VonNeumann:
+			width := radius - dy
+			if dy < 0 {
+				width = radius + dy
+			}
+			s = span{-width, width}
+		case Circular:
+			width := 0
+			for (width+1)*(width+1)+dy*dy <= radius*radius+radius {
+				width++
+			}
+			s = span{-width, width}
+		case Hexagonal:
+			if dy < 0 {
+				s.to += dy
+			} else {
+				s.from += dy
+			}
+		}
+		spans[dy+radius] = s
+	}
+	return spans
+}
+
+// Neighbourhood gets the cells of the shape around a cell, not including the cell itself, row by row from the top left
+func (shape Shape) Neighbourhood(radius int) []util.Cell {
+	var neighbourhood []util.Cell
+	for i, s := range shape.spans(radius) {
+		for x := s.from; x <= s.to; x++ {
+			if y := i - radius; x != 0 || y != 0 {
+				neighbourhood = append(neighbourhood, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return neighbourhood
+}
+
+// spanRule is a Rule that only depends on the number of alive cells in a neighbourhood with a span of columns on each row.
+// Workers count them with running sums along each row, so the time taken grows with the radius rather than its square.
+type spanRule interface {
+	Rule
+	// spans gets the columns counted on each row of the neighbourhood
+	spans() []span
+	// nextCount gets the next state of a cell from its state and the number of alive cells in the spans, including itself
+	nextCount(state byte, count int) byte
+}
+
+// LargerThanLifeRule is a Larger than Life rule, counting the alive cells within a radius of each cell, such as Bosco's Rule.
+// Dead cells are born when the count is in the birth interval and alive cells survive when it is in the survival interval.
+// With more than 2 states, alive cells that do not survive start dying as in a GenerationsRule.
+type LargerThanLifeRule struct {
+	Radius                   int
+	Shape                    Shape
+	Middle                   bool // whether a cell counts itself
+	Generations              int  // the number of states, including dead and alive
+	BirthMin, BirthMax       int
+	SurvivalMin, SurvivalMax int
+}
+
+// ParseLargerThanLife parses a rulestring in Golly's notation, such as "R5,C0,M1,S34..58,B34..45,NM" (Bosco's Rule).
+// C is the number of states (0 meaning 2), M is 1 if a cell counts itself
+// and N is the shape: M (Moore), N (von Neumann), C (circular) or H (hexagonal).
+// C, M and N default to 0, 0 and M. S and B may be a single count instead of an interval.
+func ParseLargerThanLife(rulestring string) (LargerThanLifeRule, error) {
+	rule := LargerThanLifeRule{Generations: 2, BirthMin: -1, SurvivalMin: -1}
+	for _, part := range strings.Split(strings.ToUpper(strings.TrimSpace(rulestring)), ",") {
+		if part == "" {
+			return rule, fmt.Errorf("rule %q: empty part", rulestring)
+		}
+		key, value := part[:1], part[1:]
+		var err error
+		switch key {
+		case "R":
+			rule.Radius, err = strconv.Atoi(value)
+			if err == nil && (rule.Radius < 1 || rule.Radius > 500) {
+				err = fmt.Errorf("radius %v is not in 1-500", rule.Radius)
+			}
+		case "C":
+			rule.Generations, err = strconv.Atoi(value)
+			if err == nil && (rule.Generations == 1 || rule.Generations < 0 || rule.Generations > 256) {
+				err = fmt.Errorf("states %v is not 0 or in 2-256", rule.Generations)
+			}
+			if rule.Generations == 0 {
+				rule.Generations = 2
+			}
+		case "M":
+			if value != "0" && value != "1" {
+				err = fmt.Errorf("middle %q is not 0 or 1", value)
+			}
+			rule.Middle = value == "1"
+		case "S":
+			rule.SurvivalMin, rule.SurvivalMax, err = parseInterval(value)
+		case "B":
+			rule.BirthMin, rule.BirthMax, err = parseInterval(value)
+		case "N":
+			var ok bool
+			rule.Shape, ok = shapeLetters[value]
+			if !ok {
+				err = fmt.Errorf("shape %q is not M, N, C or H", value)
+			}
+		default:
+			err = fmt.Errorf("unknown part %q", part)
+		}
+		if err != nil {
+			return rule, fmt.Errorf("rule %q: %v", rulestring, err)
+		}
+	}
+	if rule.Radius == 0 || rule.BirthMin < 0 || rule.SurvivalMin < 0 {
+		return rule, fmt.Errorf("rule %q: expected R<radius>,S<min>..<max>,B<min>..<max>", rulestring)
+	}
+	return rule, nil
+}
+
+// parse "min..max" or a single count
+func parseInterval(interval string) (int, int, error) {
+	bounds := strings.Split(interval, "..")
+	if len(bounds) > 2 {
+		return 0, 0, fmt.Errorf("interval %q: expected <min>..<max>", interval)
+	}
+	min, err := strconv.Atoi(bounds[0])
+	if err != nil || min < 0 {
+		return 0, 0, fmt.Errorf("interval %q: expected <min>..<max>", interval)
+	}
+	max := min
+	if len(bounds) == 2 {
+		max, err = strconv.Atoi(bounds[1])
+		if err != nil || max < min {
+			return 0, 0, fmt.Errorf("interval %q: expected <min>..<max>", interval)
+		}
+	}
+	return min, max, nil
+}
+
+// String gives the rulestring of the rule in Golly's notation
+func (rule LargerThanLifeRule) String() string {
+	generations := rule.Generations
+	if generations == 2 {
+		generations = 0
+	}
+	middle := 0
+	if rule.Middle {
+		middle = 1
+	}
+	shape := "M"
+	for letter, s := range shapeLetters {
+		if s == rule.Shape {
+			shape = letter
+		}
+	}
+	return fmt.Sprintf("R%d,C%d,M%d,S%d..%d,B%d..%d,N%s",
+		rule.Radius, generations, middle, rule.SurvivalMin, rule.SurvivalMax, rule.BirthMin, rule.BirthMax, shape)
+}
+
+func (rule LargerThanLifeRule) States() int {
+	return rule.Generations
+}
+
+func (rule LargerThanLifeRule) Neighbourhood() []util.Cell {
+	return rule.Shape.Neighbourhood(rule.Radius)
+}
+
+func (rule LargerThanLifeRule) Next(state byte, neighbours []byte) byte {
+	count := 0
+	if state == 1 {
+		count++
+	}
+	for _, neighbour := range neighbours {
+		if neighbour == 1 {
+			count++
+		}
+	}
+	return rule.nextCount(state, count)
+}
+
+func (rule LargerThanLifeRule) spans() []span {
+	return rule.Shape.spans(rule.Radius)
+}
+
+func (rule LargerThanLifeRule) nextCount(state byte, count int) byte {
+	if state == 1 && !rule.Middle {
+		count--
+	}
+	switch state {
+	case 0:
+		if count >= rule.BirthMin && count <= rule.BirthMax {
+			return 1
+		}
+		return 0
+	case 1:
+		if count >= rule.SurvivalMin && count <= rule.SurvivalMax {
+			return 1
+		}
+	}
+	// alive cells that did not survive and dying cells move on to the next state
+	return byte((int(state) + 1) % rule.Generations)
+}
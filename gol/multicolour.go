@@ -0,0 +1,120 @@
+package gol
+
+import (
+	"fmt"
+	"image/color"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// ColourRule is a B/S rule where every alive cell has one of 2-4 colours, such as Immigration and QuadLife.
+// The colour is held in the state of the cell: state 0 is dead and states 1 to Colours are alive in each colour.
+// Cells of every colour count as neighbours and keep their colour while they survive.
+// A cell that is born takes the colour most of its alive neighbours have. If colours are tied,
+// it takes the first colour none of them have, as in QuadLife when the 3 parents all differ,
+// or the first of the tied colours when every colour is used.
+type ColourRule struct {
+	LifeRule
+	Colours int
+}
+
+// ready made colour rules for Params.Automaton, which can also be given by name
+var (
+	Immigration = ColourRule{LifeRule: Conway, Colours: 2}
+	QuadLife    = ColourRule{LifeRule: Conway, Colours: 4}
+)
+
+// ColourPalette is the colouring of a ColourRule: black for dead cells, then red, blue, green and yellow
+var ColourPalette = []color.RGBA{
+	{R: 0x00, G: 0x00, B: 0x00, A: 0xFF},
+	{R: 0xFF, G: 0x00, B: 0x00, A: 0xFF},
+	{R: 0x00, G: 0x00, B: 0xFF, A: 0xFF},
+	{R: 0x00, G: 0xFF, B: 0x00, A: 0xFF},
+	{R: 0xFF, G: 0xFF, B: 0x00, A: 0xFF},
+}
+
+func (rule ColourRule) String() string {
+	switch rule {
+	case Immigration:
+		return "Immigration"
+	case QuadLife:
+		return "QuadLife"
+	}
+	return fmt.Sprintf("%v in %d colours", rule.LifeRule, rule.Colours)
+}
+
+func (rule ColourRule) States() int {
+	return rule.Colours + 1
+}
+
+func (rule ColourRule) Neighbourhood() []util.Cell {
+	return MooreNeighbourhood(1)
+}
+
+func (rule ColourRule) Next(state byte, neighbours []byte) byte {
+	count := 0
+	var colours [256]int
+	for _, neighbour := range neighbours {
+		if neighbour != 0 {
+			count++
+			colours[neighbour]++
+		}
+	}
+	if state != 0 {
+		if rule.Survival[count] {
+			return state
+		}
+		return 0
+	}
+	if !rule.Birth[count] {
+		return 0
+	}
+	majority, tied := byte(1), false
+	for colour := 2; colour <= rule.Colours; colour++ {
+		if colours[colour] > colours[majority] {
+			majority, tied = byte(colour), false
+		} else if colours[colour] == colours[majority] {
+			tied = true
+		}
+	}
+	if tied {
+		for colour := 1; colour <= rule.Colours; colour++ {
+			if colours[colour] == 0 {
+				return byte(colour)
+			}
+		}
+	}
+	return majority
+}
+
+func (rule ColourRule) Palette() []color.RGBA {
+	if rule.Colours < len(ColourPalette) {
+		return ColourPalette[:rule.Colours+1]
+	}
+	return ColourPalette
+}
+
+// get which states count as alive under a rule: every colour of a ColourRule, otherwise only state 1
+func aliveStates(rule Rule) [256]bool {
+	var alive [256]bool
+	if colours, ok := rule.(ColourRule); ok {
+		for state := 1; state <= colours.Colours; state++ {
+			alive[state] = true
+		}
+	} else {
+		alive[1] = true
+	}
+	return alive
+}
+
+// count the cells of the world in each colour of a ColourRule, given as grey levels
+func countColours(world [][]byte, rule ColourRule) []int {
+	states := greyStates(rule.States())
+	counts := make([]int, rule.States())
+	for _, row := range world {
+		for _, grey := range row {
+			counts[states[grey]]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,267 @@
+package gol
+
+import (
+	"fmt"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// check a run can be sent to a broker
+func checkRemote(p Params, rule Rule) error {
+	if p.Engine != StripEngine || p.ImageDepth > 0 {
+		return fmt.Errorf("a broker only runs the strip engine in 2D")
+	}
+	if _, ok := rule.(ElementaryRule); ok {
+		return fmt.Errorf("a broker does not run 1D rules")
+	}
+	if p.Automaton != nil {
+		return fmt.Errorf("rule %v cannot be sent to a broker, give it by Params.Rule", p.Automaton)
+	}
+	return nil
+}
+
+// how many times a call to the broker is tried, reconnecting in between, before the run is given up
+const (
+	brokerAttempts = 3
+	brokerRetry    = 500 * time.Millisecond
+)
+
+// pauser is an engine that computes turns on its own between steps, so has to be told when the run is paused
+type pauser interface {
+	pause(paused bool)
+}
+
+// detacher is an engine whose run can carry on without this controller
+type detacher interface {
+	// leave the run going for another controller to attach to
+	detach()
+	// end the run and shut down every process computing it
+	shutdown()
+	// get the error the run was lost to, if the engine could not carry on with it
+	lost() error
+}
+
+// remoteEngine runs the turns on the workers of a broker, see Params.Broker.
+// The broker computes the turns on its own, and each step fetches the next frame of changes it has made,
+// covering every turn since the last one. The engine keeps its own copy of the world up to date with the frames,
+// so the cells flipped can be sent as events for the GUI as they are for a local engine.
+// When the broker cannot be reached again the run ends early, as if 'q' had been pressed.
+type remoteEngine struct {
+	p      Params
+	c      distributorChannels
+	rule   Rule
+	states [][]byte
+	greys  []byte
+	alive  [256]bool
+	err    error
+	// where the run was when this controller attached to it, see Params.Attach
+	turn int
+	// guards the connection and whether this controller has paused the run,
+	// as the keypress parser pauses it during a step
+	mu     sync.Mutex
+	client *rpc.Client
+	paused bool
+}
+
+// connect to the broker and hand it the world, which it starts computing straight away
+func newRemoteEngine(p Params, world [][]byte, rule Rule, c distributorChannels) (*remoteEngine, error) {
+	if err := checkRemote(p, rule); err != nil {
+		return nil, err
+	}
+	client, err := rpc.Dial("tcp", p.Broker)
+	if err != nil {
+		return nil, err
+	}
+	lookup := greyStates(rule.States())
+	states := createNewSlice(p.ImageHeight, p.ImageWidth)
+	for i, row := range world {
+		for j, grey := range row {
+			states[i][j] = lookup[grey]
+		}
+	}
+	if err := client.Call("Broker.Start", StartRequest{Params: p, World: states}, new(Empty)); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &remoteEngine{
+		p:      p,
+		c:      c,
+		rule:   rule,
+		client: client,
+		states: states,
+		greys:  stateGreys(rule.States()),
+		alive:  aliveStates(rule),
+	}, nil
+}
+
+// connect to the broker and attach to the run already there, which has to have the size given in p.
+// The engine holds the params of the run instead
+func attachRemoteEngine(p Params, c distributorChannels) (*remoteEngine, error) {
+	client, err := rpc.Dial("tcp", p.Broker)
+	if err != nil {
+		return nil, err
+	}
+	var res AttachResponse
+	if err := client.Call("Broker.Attach", Empty{}, &res); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if res.Params.ImageWidth != p.ImageWidth || res.Params.ImageHeight != p.ImageHeight {
+		client.Close()
+		return nil, fmt.Errorf("the run on the broker is %dx%d, not %dx%d",
+			res.Params.ImageWidth, res.Params.ImageHeight, p.ImageWidth, p.ImageHeight)
+	}
+	rule, err := selectRule(res.Params)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	res.Params.Broker, res.Params.Attach = p.Broker, true
+	return &remoteEngine{
+		p:      res.Params,
+		c:      c,
+		rule:   rule,
+		client: client,
+		states: res.World,
+		greys:  stateGreys(rule.States()),
+		alive:  aliveStates(rule),
+		turn:   res.Turn,
+		paused: res.Paused,
+	}, nil
+}
+
+// call a method of the broker, reconnecting to it if the connection is lost
+func (e *remoteEngine) call(method string, args interface{}, reply interface{}) error {
+	var err error
+	for attempt := 0; attempt < brokerAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(brokerRetry)
+			client, dialErr := rpc.Dial("tcp", e.p.Broker)
+			if dialErr != nil {
+				err = dialErr
+				continue
+			}
+			e.mu.Lock()
+			e.client.Close()
+			e.client = client
+			e.mu.Unlock()
+		}
+		e.mu.Lock()
+		client := e.client
+		e.mu.Unlock()
+		err = client.Call(method, args, reply)
+		// errors returned by the broker itself will not go away by calling again
+		if _, ok := err.(rpc.ServerError); err == nil || ok {
+			return err
+		}
+	}
+	return err
+}
+
+// apply the next frame, which can be several turns if the broker got ahead.
+// Every turn merged into the frame still gets a TurnComplete event, before the cells flipped in the frame.
+// Returns 0 if this controller pauses the run before the broker gets to another turn, or the frame cannot be fetched
+func (e *remoteEngine) step(turn, turns int) int {
+	var frame FrameResponse
+	var diff []byte
+	for {
+		e.err = e.call("Broker.Frame", Empty{}, &frame)
+		if e.err == nil {
+			diff, e.err = decompressDiff(frame.Diff, e.p.ImageWidth*e.p.ImageHeight)
+		}
+		if e.err != nil {
+			fmt.Println("Lost the broker:", e.err)
+			return 0
+		}
+		if frame.Turn > turn {
+			break
+		}
+		// the run is paused, so let the distributor wait for the keypress parser if it was paused from here
+		e.mu.Lock()
+		paused := e.paused
+		e.mu.Unlock()
+		if paused {
+			return 0
+		}
+		time.Sleep(brokerRetry)
+	}
+	for merged := turn; merged < frame.Turn-1; merged++ {
+		e.c.events <- TurnComplete{CompletedTurns: merged}
+	}
+	flips := newStateSender(e.p, e.c, frame.Turn-1, e.rule)
+	for k, change := range diff {
+		if change != 0 {
+			i, j := k/e.p.ImageWidth, k%e.p.ImageWidth
+			from := e.states[i][j]
+			e.states[i][j] ^= change
+			flips.change(util.Cell{X: j, Y: i}, from, e.states[i][j])
+		}
+	}
+	flips.send()
+	return frame.Turn - turn
+}
+
+func (e *remoteEngine) world() [][]byte {
+	world := createNewSlice(e.p.ImageHeight, e.p.ImageWidth)
+	for i, row := range e.states {
+		for j, state := range row {
+			world[i][j] = e.greys[state]
+		}
+	}
+	return world
+}
+
+func (e *remoteEngine) aliveCount() int {
+	return len(e.aliveCells())
+}
+
+func (e *remoteEngine) aliveCells() []util.Cell {
+	var aliveCells []util.Cell
+	for i, row := range e.states {
+		for j, state := range row {
+			if e.alive[state] {
+				aliveCells = append(aliveCells, util.Cell{X: j, Y: i})
+			}
+		}
+	}
+	return aliveCells
+}
+
+// pause or resume the turns on the broker. If it cannot be reached the next step ends the run
+func (e *remoteEngine) pause(paused bool) {
+	e.mu.Lock()
+	e.paused = paused
+	e.mu.Unlock()
+	if err := e.call("Broker.Pause", PauseRequest{Paused: paused}, new(Empty)); err != nil {
+		fmt.Println("Lost the broker:", err)
+	}
+}
+
+// stop the run on the broker, which may already be gone if the run ended early
+func (e *remoteEngine) stop() {
+	e.client.Call("Broker.Stop", Empty{}, new(Empty))
+	e.client.Close()
+}
+
+func (e *remoteEngine) lost() error {
+	return e.err
+}
+
+// leave the broker computing the run without this controller
+func (e *remoteEngine) detach() {
+	e.client.Close()
+}
+
+// shut down the broker and its workers
+func (e *remoteEngine) shutdown() {
+	// the broker can close the connection before it answers as it stops serving
+	if err := e.client.Call("Broker.Shutdown", Empty{}, new(Empty)); err != nil {
+		if _, ok := err.(rpc.ServerError); ok {
+			fmt.Println("Could not shut down the broker:", err)
+		}
+	}
+	e.client.Close()
+}
@@ -0,0 +1,309 @@
+package gol
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// workerServer is the RPC service of a worker process, which keeps the strip of the world a broker gives it.
+// Like the workers of the StripEngine it exchanges the cells beyond the edges of its strip with the workers
+// that own them, usually the ones above and below, sending them straight to their RPC services after each turn.
+// The strip is split again between Params.Threads goroutines, each with its own kernel.
+type workerServer struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	p       Params
+	rule    Rule
+	w       *worker
+	parts   []HorSlice // rows of the strip computed by each goroutine, counted from its first row
+	kernels []rowKernel
+	peers   []*rpc.Client // peers[to] is connected to worker to, if it is sent halo cells
+	// in[from] carries the halo cells sent by worker from, guarded by inMu as they arrive during a Step.
+	// abort is closed when the broker gives up on the turn, as a worker it needs cells from has failed
+	inMu  sync.Mutex
+	in    []chan HaloRequest
+	abort chan bool
+}
+
+// errAborted is returned by a turn aborted by the broker
+var errAborted = errors.New("the turn was aborted")
+
+// ServeWorker registers a worker listening on listener with the broker at the given address,
+// then serves the calls of the broker and the other workers in the background until the listener is closed,
+// which the worker does itself when the broker shuts it down.
+func ServeWorker(listener net.Listener, broker string) error {
+	server := rpc.NewServer()
+	util.Check(server.RegisterName("Worker", &workerServer{listener: listener}))
+	client, err := rpc.Dial("tcp", broker)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	go server.Accept(listener)
+	return client.Call("Broker.Register", RegisterRequest{Address: listener.Addr().String()}, new(Empty))
+}
+
+// Start a run, loading the strip and connecting to the workers that need its cells
+func (s *workerServer) Start(req StripRequest, res *Empty) error {
+	rule, err := selectRule(req.Params)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closePeers()
+
+	strips := make([]HorSlice, len(req.Strips))
+	for id, strip := range req.Strips {
+		strips[id] = HorSlice{startRow: strip.StartRow, endRow: strip.EndRow}
+	}
+	radius := neighbourhoodRadius(rule.Neighbourhood())
+	s.p, s.rule = req.Params, rule
+	s.w = newWorker(req.ID, strips[req.ID], radius, req.Params.ImageWidth, len(strips), nil)
+	if len(req.Buffer) != len(s.w.cur) {
+		return errors.New("the strip does not match the rows of the worker")
+	}
+	s.w.cur = req.Buffer
+
+	rows := strips[req.ID].endRow - strips[req.ID].startRow
+	threads := req.Params.Threads
+	if threads > rows {
+		threads = rows
+	}
+	if threads < 1 {
+		threads = 1
+	}
+	s.parts = splitRows(rows, threads)
+	s.kernels = make([]rowKernel, threads)
+	for k := range s.kernels {
+		s.kernels[k] = newRowKernel(req.Params.Kernel, rule)
+	}
+
+	if req.Params.Gather {
+		return nil
+	}
+	t := newTopology(req.Params)
+	for to := range strips {
+		for from, cells := range haloCells(t, strips, radius, to) {
+			if to == s.w.id {
+				s.w.receives[from] = cells
+			} else if from == s.w.id {
+				s.w.sends[to] = cells
+			}
+		}
+	}
+	s.peers = make([]*rpc.Client, len(strips))
+	for to, cells := range s.w.sends {
+		if to != s.w.id && len(cells) > 0 {
+			if s.peers[to], err = rpc.Dial("tcp", req.Strips[to].Address); err != nil {
+				return err
+			}
+		}
+	}
+	s.inMu.Lock()
+	s.in = make([]chan HaloRequest, len(strips))
+	for from := range s.in {
+		// one message per turn, which is always received before the next turn starts
+		s.in[from] = make(chan HaloRequest, 1)
+	}
+	s.abort = make(chan bool)
+	s.inMu.Unlock()
+	return nil
+}
+
+// Step computes the next turn of the strip, then swaps halo cells with the other workers.
+// The changes to the strip are sent back for the broker's frames
+func (s *workerServer) Step(req StepRequest, res *DiffResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil || s.p.Gather {
+		return errors.New("the worker has not been given a strip to keep")
+	}
+	old := s.w.strip()
+	s.evolve(req.Turn)
+	diff, err := compressDiff(xorRows(make([]byte, 0, len(old)*s.p.ImageWidth), old, s.w.strip()))
+	if err != nil {
+		return err
+	}
+	res.Diff = diff
+	return s.exchange(req.Turn + 1)
+}
+
+// Halo receives the cells of another worker read beyond the edges of the strip
+func (s *workerServer) Halo(req HaloRequest, res *Empty) error {
+	s.inMu.Lock()
+	in, abort := s.in, s.abort
+	s.inMu.Unlock()
+	if req.From < 0 || req.From >= len(in) {
+		return fmt.Errorf("no strip %d", req.From)
+	}
+	select {
+	case in[req.From] <- req:
+		return nil
+	case <-abort:
+		return errAborted
+	}
+}
+
+// Abort the turn being computed, which the broker starts again from a checkpoint
+func (s *workerServer) Abort(req Empty, res *Empty) error {
+	s.inMu.Lock()
+	defer s.inMu.Unlock()
+	if s.abort != nil {
+		select {
+		case <-s.abort:
+		default:
+			close(s.abort)
+		}
+	}
+	return nil
+}
+
+// Heartbeat answers straight away, even during a turn, to show the worker is alive
+func (s *workerServer) Heartbeat(req Empty, res *Empty) error {
+	return nil
+}
+
+// Evolve computes the next turn of a strip that the broker sends whole, see Params.Gather
+func (s *workerServer) Evolve(req EvolveRequest, res *StripResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return errors.New("the worker has not been given a strip")
+	}
+	if len(req.Buffer) != len(s.w.cur) {
+		return errors.New("the strip does not match the rows of the worker")
+	}
+	s.w.cur = req.Buffer
+	s.evolve(req.Turn)
+	res.Strip = s.w.strip()
+	return nil
+}
+
+// Strip gets the rows of the strip
+func (s *workerServer) Strip(req Empty, res *StripResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return errors.New("the worker has not been given a strip")
+	}
+	res.Strip = s.w.strip()
+	return nil
+}
+
+// Shutdown forgets the strip and stops serving
+func (s *workerServer) Shutdown(req Empty, res *Empty) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closePeers()
+	s.w = nil
+	return s.listener.Close()
+}
+
+// compute the next state of every cell of the strip with one goroutine per part, making it the current buffer
+func (s *workerServer) evolve(turn int) {
+	w := s.w
+	w.next = createNewSlice(len(w.cur), len(w.cur[0]))
+	var wg sync.WaitGroup
+	for k, part := range s.parts {
+		// the buffers of each part share the rows of the strip and its halo
+		view := &worker{
+			slice:  HorSlice{startRow: w.slice.startRow + part.startRow, endRow: w.slice.startRow + part.endRow},
+			radius: w.radius,
+			cur:    w.cur[part.startRow : part.endRow+2*w.radius],
+			next:   w.next[part.startRow : part.endRow+2*w.radius],
+		}
+		wg.Add(1)
+		go func(kernel rowKernel) {
+			view.compute(s.p, s.rule, kernel, turn)
+			wg.Done()
+		}(s.kernels[k])
+	}
+	wg.Wait()
+	w.cur, w.next = w.next, nil
+}
+
+// send the cells other workers need from this strip after turn turns and fill the halo with the cells they send back
+func (s *workerServer) exchange(turn int) error {
+	w := s.w
+	var calls []*rpc.Call
+	for to, cells := range w.sends {
+		if to != w.id && len(cells) > 0 {
+			halo := HaloRequest{From: w.id, Turn: turn, Values: w.values(cells)}
+			calls = append(calls, s.peers[to].Go("Worker.Halo", halo, new(Empty), nil))
+		}
+	}
+	// the strip may wrap around onto itself, e.g. the left and right columns or a single worker
+	for _, h := range w.receives[w.id] {
+		w.cur[h.row][h.column] = w.at(h.source)
+	}
+	for from, cells := range w.receives {
+		if from == w.id || len(cells) == 0 {
+			continue
+		}
+		var halo HaloRequest
+		select {
+		case halo = <-s.in[from]:
+		case <-s.abort:
+			return errAborted
+		}
+		if halo.Turn != turn || len(halo.Values) != len(cells) {
+			return fmt.Errorf("worker %d sent %d cells after turn %d, expected %d after turn %d", from, len(halo.Values), halo.Turn, len(cells), turn)
+		}
+		for k, h := range cells {
+			w.cur[h.row][h.column] = halo.Values[k]
+		}
+	}
+	for _, call := range calls {
+		select {
+		case <-call.Done:
+		case <-s.abort:
+			return errAborted
+		}
+		if call.Error != nil {
+			return call.Error
+		}
+	}
+	return nil
+}
+
+// close the connections to the other workers of the last run
+func (s *workerServer) closePeers() {
+	for _, peer := range s.peers {
+		if peer != nil {
+			peer.Close()
+		}
+	}
+	s.peers = nil
+}
+
+// get the rows of the strip, without the halo
+func (w *worker) strip() [][]byte {
+	strip := make([][]byte, w.slice.endRow-w.slice.startRow)
+	for i := range strip {
+		strip[i] = w.cur[i+w.radius][w.radius : len(w.cur[i])-w.radius]
+	}
+	return strip
+}
+
+// compute every cell of the strip into the next buffer, whose halo is left alone
+func (w *worker) compute(p Params, rule Rule, kernel rowKernel, turn int) {
+	width := len(w.cur[0]) - 2*w.radius
+	kernel.begin(w)
+	for i := w.slice.startRow; i < w.slice.endRow; i++ {
+		bi := i - w.slice.startRow + w.radius
+		kernel.row(w, bi, 0, width)
+		if p.Stochastic {
+			for j := 0; j < width; j++ {
+				bj := j + w.radius
+				w.next[bi][bj] = stochasticNext(p, rule.States(), turn, j, i, w.cur[bi][bj], w.next[bi][bj])
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package gol
+
+// the arguments and replies of the RPC methods of the broker and its workers, sent with encoding/gob.
+// The worlds hold the state of each cell, not its grey level.
+
+// Empty is the argument or reply of a method that does not need one
+type Empty struct{}
+
+// RegisterRequest adds a worker listening at Address to the broker
+type RegisterRequest struct {
+	Address string
+}
+
+// StartRequest hands the initial world of a run to the broker
+type StartRequest struct {
+	Params Params
+	World  [][]byte
+}
+
+// FrameResponse gives the changes to the world since the last frame, up to Turn turns.
+// Diff is the XOR of the old and new state of every cell, row by row, compressed with compress/flate.
+// A controller that falls behind is sent one frame for all the turns it missed,
+// and one that catches up with a paused run is sent a frame with no turns in it
+type FrameResponse struct {
+	Turn int
+	Diff []byte
+}
+
+// PauseRequest pauses or resumes the run of the broker. A pause with a Turn waits for a run to complete
+// at least Turn turns and pauses it straight after, even if the run has not been started yet
+type PauseRequest struct {
+	Paused bool
+	Turn   int
+}
+
+// AttachResponse gives a controller reattaching to a broker the run it left, after Turn turns,
+// and whether it is paused. Frames sent after it carry on from World
+type AttachResponse struct {
+	Params Params
+	World  [][]byte
+	Turn   int
+	Paused bool
+}
+
+// WorkerStrip is the rows of the world computed by the worker listening at Address
+type WorkerStrip struct {
+	Address  string
+	StartRow int
+	EndRow   int
+}
+
+// StripRequest gives worker ID its strip of Strips, surrounded by the cells it reads beyond its edges,
+// and the strips of the other workers, which it exchanges those cells with directly
+type StripRequest struct {
+	Params Params
+	ID     int
+	Strips []WorkerStrip
+	Buffer [][]byte
+}
+
+// StepRequest has a worker compute the turn after Turn
+type StepRequest struct {
+	Turn int
+}
+
+// DiffResponse gives the changes to a worker's strip in a turn, compressed like FrameResponse.Diff
+type DiffResponse struct {
+	Diff []byte
+}
+
+// HaloRequest sends the cells of worker From's strip after Turn turns that the receiving worker reads beyond its edges
+type HaloRequest struct {
+	From   int
+	Turn   int
+	Values []byte
+}
+
+// EvolveRequest gives a worker its strip, with the cells it reads beyond the edges around it,
+// to compute the turn after Turn, see Params.Gather
+type EvolveRequest struct {
+	Turn   int
+	Buffer [][]byte
+}
+
+// StripResponse gives the rows of a worker's strip
+type StripResponse struct {
+	Strip [][]byte
+}
@@ -0,0 +1,197 @@
+package gol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// ConwayRule is the rulestring of the standard Game of Life, used when Params.Rule is empty
+const ConwayRule = "B3/S23"
+
+// LifeRule is an outer-totalistic rule, indexed by the number of alive neighbours (0-8)
+type LifeRule struct {
+	Birth    [9]bool
+	Survival [9]bool
+}
+
+// ParseRule parses a B/S rulestring such as "B36/S23" (HighLife) or "B2/S" (Seeds).
+// The older S/B notation ("23/36") is also accepted. An empty rulestring gives Conway's rule.
+func ParseRule(rulestring string) (LifeRule, error) {
+	var rule LifeRule
+	if rulestring == "" {
+		rulestring = ConwayRule
+	}
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(rulestring)), "/")
+	if len(parts) != 2 {
+		return rule, fmt.Errorf("rule %q: expected B<digits>/S<digits>", rulestring)
+	}
+	birth, survival := parts[0], parts[1]
+	switch {
+	case strings.HasPrefix(birth, "B") && strings.HasPrefix(survival, "S"):
+		birth, survival = birth[1:], survival[1:]
+	case strings.HasPrefix(birth, "S") && strings.HasPrefix(survival, "B"):
+		birth, survival = survival[1:], birth[1:]
+	case !strings.ContainsAny(birth+survival, "BS"):
+		// S/B notation lists survival first
+		birth, survival = survival, birth
+	default:
+		return rule, fmt.Errorf("rule %q: expected B<digits>/S<digits>", rulestring)
+	}
+	if err := parseCounts(birth, &rule.Birth); err != nil {
+		return rule, fmt.Errorf("rule %q: birth %v", rulestring, err)
+	}
+	if err := parseCounts(survival, &rule.Survival); err != nil {
+		return rule, fmt.Errorf("rule %q: survival %v", rulestring, err)
+	}
+	return rule, nil
+}
+
+// set each neighbour count listed in digits
+func parseCounts(digits string, counts *[9]bool) error {
+	for _, d := range digits {
+		if d < '0' || d > '8' {
+			return fmt.Errorf("count %q is not in 0-8", d)
+		}
+		if counts[d-'0'] {
+			return fmt.Errorf("count %q is repeated", d)
+		}
+		counts[d-'0'] = true
+	}
+	return nil
+}
+
+// String gives the canonical B/S rulestring of the rule
+func (rule LifeRule) String() string {
+	var b strings.Builder
+	b.WriteString("B")
+	for n, born := range rule.Birth {
+		if born {
+			fmt.Fprint(&b, n)
+		}
+	}
+	b.WriteString("/S")
+	for n, survives := range rule.Survival {
+		if survives {
+			fmt.Fprint(&b, n)
+		}
+	}
+	return b.String()
+}
+
+// apply the rule to a cell given its current state (0 or 1) and number of alive neighbours
+func (rule LifeRule) next(state byte, neighbourCount int) byte {
+	if state == 1 {
+		if rule.Survival[neighbourCount] {
+			return 1
+		}
+		return 0
+	}
+	if rule.Birth[neighbourCount] {
+		return 1
+	}
+	return 0
+}
+
+func (rule LifeRule) States() int {
+	return 2
+}
+
+func (rule LifeRule) Neighbourhood() []util.Cell {
+	return MooreNeighbourhood(1)
+}
+
+func (rule LifeRule) Next(state byte, neighbours []byte) byte {
+	count := 0
+	for _, neighbour := range neighbours {
+		count += int(neighbour)
+	}
+	return rule.next(state, count)
+}
+
+// GenerationsRule is a rule from the Generations family, such as Brian's Brain ("B2/S/C3") or Star Wars ("B2/S345/C4").
+// Alive cells that do not survive start dying instead of dying straight away, moving through states 2 to States-1
+// before becoming dead. Only alive cells (state 1) count as neighbours, and dying cells cannot be born again.
+type GenerationsRule struct {
+	LifeRule
+	Generations int // the number of states, including dead and alive
+}
+
+// ParseGenerations parses a B/S/C rulestring such as "B2/S/C3". The older S/B/C notation ("/2/3") is also accepted.
+func ParseGenerations(rulestring string) (GenerationsRule, error) {
+	var rule GenerationsRule
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(rulestring)), "/")
+	if len(parts) != 3 {
+		return rule, fmt.Errorf("rule %q: expected B<digits>/S<digits>/C<states>", rulestring)
+	}
+	life, err := ParseRule(parts[0] + "/" + parts[1])
+	if err != nil {
+		return rule, err
+	}
+	generations, err := strconv.Atoi(strings.TrimPrefix(parts[2], "C"))
+	if err != nil || generations < 2 || generations > 256 {
+		return rule, fmt.Errorf("rule %q: states %q is not in 2-256", rulestring, parts[2])
+	}
+	return GenerationsRule{LifeRule: life, Generations: generations}, nil
+}
+
+// ParseRulestring parses a B/S rulestring into a LifeRule, a B/S/C rulestring into a GenerationsRule,
+// an R<radius>,... rulestring into a LargerThanLifeRule, a B/S rulestring ending with H into a HexRule,
+// a B/S rulestring with letters in Hensel notation into an IsotropicRule, W<number> into an ElementaryRule
+// or a rulestring of digits in Bays' notation, such as "4555", into a BaysRule.
+// The ready made rules can also be given by name, e.g. "Wireworld" or "Brian's Brain".
+func ParseRulestring(rulestring string) (Rule, error) {
+	if rule, ok := namedRule(rulestring); ok {
+		return rule, nil
+	}
+	trimmed := strings.ToUpper(strings.TrimSpace(rulestring))
+	if strings.HasPrefix(trimmed, "R") {
+		return ParseLargerThanLife(rulestring)
+	}
+	if strings.HasPrefix(trimmed, "W") {
+		return ParseElementary(rulestring)
+	}
+	if trimmed != "" && strings.Trim(trimmed, "0123456789,") == "" {
+		return ParseBays(rulestring)
+	}
+	if strings.HasSuffix(trimmed, "H") {
+		return ParseHexRule(rulestring)
+	}
+	if strings.Count(rulestring, "/") == 2 {
+		return ParseGenerations(rulestring)
+	}
+	if strings.ContainsAny(strings.ToLower(trimmed), "-"+henselLetters[4]) {
+		return ParseIsotropic(rulestring)
+	}
+	return ParseRule(rulestring)
+}
+
+// String gives the canonical B/S/C rulestring of the rule
+func (rule GenerationsRule) String() string {
+	return fmt.Sprintf("%v/C%d", rule.LifeRule, rule.Generations)
+}
+
+func (rule GenerationsRule) States() int {
+	return rule.Generations
+}
+
+func (rule GenerationsRule) Next(state byte, neighbours []byte) byte {
+	if state > 1 {
+		// dying cells always move on to the next state
+		return byte((int(state) + 1) % rule.Generations)
+	}
+	count := 0
+	for _, neighbour := range neighbours {
+		if neighbour == 1 {
+			count++
+		}
+	}
+	next := rule.next(state, count)
+	if state == 1 && next == 0 {
+		// alive cells that do not survive start dying
+		return byte(2 % rule.Generations)
+	}
+	return next
+}
@@ -0,0 +1,210 @@
+package gol
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// side length of the square chunks of the sparse engine, each row of a chunk is one uint64
+const chunkSize = 64
+
+// position of a chunk, covering the cells from (chunkSize*x, chunkSize*y) to (chunkSize*x+63, chunkSize*y+63)
+type chunkKey struct {
+	x, y int
+}
+
+// bit b of row r is the cell at (chunkSize*x+b, chunkSize*y+r)
+type chunk [chunkSize]uint64
+
+// sparseEngine simulates an unbounded plane, storing only the chunks that hold alive cells.
+// The image is loaded with its top left corner at (0, 0), and cells may later have negative coordinates.
+// Every turn the chunks with alive cells, and their neighbours, are split between the workers.
+type sparseEngine struct {
+	p      Params
+	rule   LifeRule
+	c      distributorChannels
+	chunks map[chunkKey]*chunk
+}
+
+func newSparseEngine(p Params, world [][]byte, rule LifeRule, c distributorChannels) (*sparseEngine, error) {
+	if p.Boundary != Torus {
+		return nil, fmt.Errorf("the sparse engine has no edges, so cannot have a %v boundary", p.Boundary)
+	}
+	if rule.Birth[0] {
+		return nil, fmt.Errorf("rule %v would fill the unbounded plane", rule)
+	}
+	e := &sparseEngine{p: p, rule: rule, c: c, chunks: make(map[chunkKey]*chunk)}
+	for i, row := range world {
+		for j, cell := range row {
+			if cell == 0xFF {
+				key := chunkKey{j >> 6, i >> 6}
+				if e.chunks[key] == nil {
+					e.chunks[key] = new(chunk)
+				}
+				e.chunks[key][i&63] |= 1 << uint(j&63)
+			}
+		}
+	}
+	return e, nil
+}
+
+// compute the next generation of the chunk at key, sending the changes to flips
+func (e *sparseEngine) evolveChunk(key chunkKey, flips *flipSender) *chunk {
+	// the chunk and its eight neighbours, [0][0] being the north west one
+	var around [3][3]*chunk
+	empty := new(chunk)
+	for dy := 0; dy < 3; dy++ {
+		for dx := 0; dx < 3; dx++ {
+			around[dy][dx] = e.chunks[chunkKey{key.x + dx - 1, key.y + dy - 1}]
+			if around[dy][dx] == nil {
+				around[dy][dx] = empty
+			}
+		}
+	}
+	// line up row r (-1 to chunkSize) with its west and east neighbours
+	shifted := func(r int) (west, centre, east uint64) {
+		dy := 1
+		if r < 0 {
+			dy, r = 0, r+chunkSize
+		} else if r >= chunkSize {
+			dy, r = 2, r-chunkSize
+		}
+		centre = around[dy][1][r]
+		west = centre<<1 | around[dy][0][r]>>63
+		east = centre>>1 | around[dy][2][r]<<63
+		return west, centre, east
+	}
+
+	next := new(chunk)
+	for r := 0; r < chunkSize; r++ {
+		aw, a, ae := shifted(r - 1)
+		hw, h, he := shifted(r)
+		bw, b, be := shifted(r + 1)
+		next[r] = evolveWord(e.rule, aw, a, ae, hw, h, he, bw, b, be)
+
+		// cellFlipped event for every changed bit
+		for flipped := next[r] ^ h; flipped != 0; flipped &= flipped - 1 {
+			x := chunkSize*key.x + bits.TrailingZeros64(flipped)
+			flips.flip(util.Cell{X: x, Y: chunkSize*key.y + r})
+		}
+	}
+	return next
+}
+
+func (c *chunk) empty() bool {
+	for _, row := range c {
+		if row != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// evolve every chunk that has alive cells or borders one, keeping only the chunks left with alive cells
+func (e *sparseEngine) step(turn, turns int) int {
+	active := make(map[chunkKey]bool)
+	for key := range e.chunks {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				active[chunkKey{key.x + dx, key.y + dy}] = true
+			}
+		}
+	}
+	keys := make([]chunkKey, 0, len(active))
+	for key := range active {
+		keys = append(keys, key)
+	}
+
+	var lock sync.Mutex
+	var waitgroup sync.WaitGroup
+	chunks := make(map[chunkKey]*chunk)
+	for _, work := range splitRows(len(keys), e.p.Threads) {
+		waitgroup.Add(1)
+		go func(work HorSlice) {
+			evolved := make(map[chunkKey]*chunk)
+			flips := newFlipSender(e.p, e.c, turn)
+			for _, key := range keys[work.startRow:work.endRow] {
+				if next := e.evolveChunk(key, flips); !next.empty() {
+					evolved[key] = next
+				}
+			}
+			flips.send()
+			lock.Lock()
+			for key, next := range evolved {
+				chunks[key] = next
+			}
+			lock.Unlock()
+			waitgroup.Done()
+		}(work)
+	}
+	waitgroup.Wait()
+	e.chunks = chunks
+	return 1
+}
+
+// get the area covered by alive cells and the loaded image, as its top left corner and size
+func (e *sparseEngine) bounds() (origin, size util.Cell) {
+	minX, minY, maxX, maxY := 0, 0, e.p.ImageWidth, e.p.ImageHeight
+	for key, c := range e.chunks {
+		for r, row := range c {
+			if row == 0 {
+				continue
+			}
+			y := chunkSize*key.y + r
+			left := chunkSize*key.x + bits.TrailingZeros64(row)
+			right := chunkSize*key.x + 63 - bits.LeadingZeros64(row)
+			if left < minX {
+				minX = left
+			}
+			if right+1 > maxX {
+				maxX = right + 1
+			}
+			if y < minY {
+				minY = y
+			}
+			if y+1 > maxY {
+				maxY = y + 1
+			}
+		}
+	}
+	return util.Cell{X: minX, Y: minY}, util.Cell{X: maxX - minX, Y: maxY - minY}
+}
+
+// get the bounding box of the alive cells and the loaded image as one byte per cell
+func (e *sparseEngine) world() [][]byte {
+	origin, size := e.bounds()
+	world := createNewSlice(size.Y, size.X)
+	for _, cell := range e.aliveCells() {
+		world[cell.Y-origin.Y][cell.X-origin.X] = 0xFF
+	}
+	return world
+}
+
+func (e *sparseEngine) aliveCount() int {
+	count := 0
+	for _, c := range e.chunks {
+		for _, row := range c {
+			count += bits.OnesCount64(row)
+		}
+	}
+	return count
+}
+
+func (e *sparseEngine) aliveCells() []util.Cell {
+	var aliveCells []util.Cell
+	for key, c := range e.chunks {
+		for r, row := range c {
+			for ; row != 0; row &= row - 1 {
+				x := chunkSize*key.x + bits.TrailingZeros64(row)
+				aliveCells = append(aliveCells, util.Cell{X: x, Y: chunkSize*key.y + r})
+			}
+		}
+	}
+	return aliveCells
+}
+
+// nothing runs between steps
+func (e *sparseEngine) stop() {}
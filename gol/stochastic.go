@@ -0,0 +1,57 @@
+package gol
+
+import (
+	"fmt"
+	"math"
+)
+
+// check the probabilities of a stochastic run
+func checkStochastic(p Params, rule Rule) error {
+	if !p.Stochastic {
+		return nil
+	}
+	if p.Engine != StripEngine {
+		return fmt.Errorf("the %v engine does not support stochastic rules", p.Engine)
+	}
+	if _, ok := rule.(ColourRule); ok {
+		return fmt.Errorf("rule %v cannot be stochastic", rule)
+	}
+	for _, probability := range []float64{p.BirthProbability, p.DeathProbability} {
+		if math.IsNaN(probability) || probability < 0 || probability > 1 {
+			return fmt.Errorf("probability %v is not in 0-1", probability)
+		}
+	}
+	return nil
+}
+
+// get a random number in [0, 1) for a cell on a turn.
+// It only depends on the seed, the turn and the position of the cell, so the result of a run
+// is the same however the world is split between workers.
+func cellRandom(seed int64, turn, x, y int) float64 {
+	h := uint64(seed)
+	for _, v := range []int{turn, y, x} {
+		// splitmix64, mixing in one value at a time
+		h += uint64(v) + 0x9E3779B97F4A7C15
+		h = (h ^ h>>30) * 0xBF58476D1CE4E5B9
+		h = (h ^ h>>27) * 0x94D049BB133111EB
+		h ^= h >> 31
+	}
+	return float64(h>>11) / (1 << 53)
+}
+
+// apply the probabilities of a stochastic run to the next state the rule gives a cell in row y, column x.
+// A cell only becomes alive with BirthProbability, and a cell that survives dies with DeathProbability,
+// starting to die in state 2 if the rule has more than 2 states.
+func stochasticNext(p Params, states, turn, x, y int, state, next byte) byte {
+	switch {
+	case state != 1 && next == 1:
+		if cellRandom(p.Seed, turn, x, y) >= p.BirthProbability {
+			return state
+		}
+	case state == 1 && next == 1:
+		if cellRandom(p.Seed, turn, x, y) < p.DeathProbability {
+			return byte(2 % states)
+		}
+	}
+	return next
+}
@@ -0,0 +1,65 @@
+package gol
+
+// side length of the square tiles used by the strip engine to skip regions of the world
+const tileSize = 4
+
+// tileActivity tracks which tiles of the world changed on the last turn.
+// A cell can only change if something in its neighbourhood changed on the turn before,
+// so only tiles that changed, or are within span tiles of one that did, need to be recomputed.
+type tileActivity struct {
+	rows, columns int
+	span          int  // tiles needed to cover the radius of the neighbourhood
+	always        bool // whether every tile is active, for rules where cells can change on their own
+	// changed[i][tc] is true if a cell in row i of tile column tc changed on the last turn.
+	// It is kept per row so that workers only ever write to the rows of their own strip.
+	changed [][]bool
+	active  [][]bool
+}
+
+func newTileActivity(width, height, radius int) *tileActivity {
+	a := &tileActivity{
+		rows:    (height + tileSize - 1) / tileSize,
+		columns: (width + tileSize - 1) / tileSize,
+		span:    (radius + tileSize - 1) / tileSize,
+	}
+	a.changed = make([][]bool, height)
+	for i := range a.changed {
+		a.changed[i] = make([]bool, a.columns)
+		// everything is computed on the first turn
+		for tc := range a.changed[i] {
+			a.changed[i][tc] = true
+		}
+	}
+	a.active = make([][]bool, a.rows)
+	for tr := range a.active {
+		a.active[tr] = make([]bool, a.columns)
+	}
+	return a
+}
+
+// work out which tiles need computing this turn from the changes made on the last one.
+// Tiles near the edge of the world are always computed, as the boundary decides their neighbours.
+func (a *tileActivity) update() {
+	tileChanged := make([][]bool, a.rows)
+	for tr := range tileChanged {
+		tileChanged[tr] = make([]bool, a.columns)
+	}
+	for i, row := range a.changed {
+		for tc, changed := range row {
+			if changed {
+				tileChanged[i/tileSize][tc] = true
+			}
+		}
+	}
+	for tr := 0; tr < a.rows; tr++ {
+		for tc := 0; tc < a.columns; tc++ {
+			active := a.always || tr < a.span || tc < a.span || tr >= a.rows-a.span || tc >= a.columns-a.span
+			for i := tr - a.span; i <= tr+a.span && !active; i++ {
+				for j := tc - a.span; j <= tc+a.span && !active; j++ {
+					active = tileChanged[i][j]
+				}
+			}
+			a.active[tr][tc] = active
+		}
+	}
+}
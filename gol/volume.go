@@ -0,0 +1,257 @@
+package gol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// BaysRule is one of Carter Bays' rules for 3D Life, such as 4555 or 5766, where each cell of a voxel world
+// has the 26 neighbours around it in its own layer and the layers above and below.
+// An alive cell survives with SurvivalMin-SurvivalMax alive neighbours and a dead cell is born with BirthMin-BirthMax.
+// It can only be run with Params.ImageDepth set.
+type BaysRule struct {
+	SurvivalMin, SurvivalMax int
+	BirthMin, BirthMax       int
+}
+
+// ParseBays parses a rulestring in Bays' notation, the survival range followed by the birth range,
+// either as 4 digits, such as "4555", or as 4 numbers of 0-26 separated by commas, such as "10,21,12,12".
+func ParseBays(rulestring string) (BaysRule, error) {
+	trimmed := strings.TrimSpace(rulestring)
+	var parts []string
+	if strings.Contains(trimmed, ",") {
+		parts = strings.Split(trimmed, ",")
+	} else {
+		parts = strings.Split(trimmed, "")
+	}
+	if len(parts) != 4 {
+		return BaysRule{}, fmt.Errorf("rule %q: expected 4 digits or 4 numbers separated by commas", rulestring)
+	}
+	var counts [4]int
+	for k, part := range parts {
+		count, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || count < 0 || count > 26 {
+			return BaysRule{}, fmt.Errorf("rule %q: count %q is not in 0-26", rulestring, part)
+		}
+		counts[k] = count
+	}
+	rule := BaysRule{SurvivalMin: counts[0], SurvivalMax: counts[1], BirthMin: counts[2], BirthMax: counts[3]}
+	if rule.SurvivalMin > rule.SurvivalMax || rule.BirthMin > rule.BirthMax {
+		return BaysRule{}, fmt.Errorf("rule %q: a range ends before it starts", rulestring)
+	}
+	return rule, nil
+}
+
+// String gives the rule in Bays' notation, with commas if any count has 2 digits
+func (rule BaysRule) String() string {
+	if rule.SurvivalMax < 10 && rule.BirthMax < 10 {
+		return fmt.Sprintf("%d%d%d%d", rule.SurvivalMin, rule.SurvivalMax, rule.BirthMin, rule.BirthMax)
+	}
+	return fmt.Sprintf("%d,%d,%d,%d", rule.SurvivalMin, rule.SurvivalMax, rule.BirthMin, rule.BirthMax)
+}
+
+func (rule BaysRule) States() int {
+	return 2
+}
+
+// Neighbourhood gets the 8 neighbours of a cell in its own layer.
+// The volume engine also passes Next the 9 cells above and below them in the layers either side, 26 in all.
+func (rule BaysRule) Neighbourhood() []util.Cell {
+	return MooreNeighbourhood(1)
+}
+
+func (rule BaysRule) Next(state byte, neighbours []byte) byte {
+	count := 0
+	for _, neighbour := range neighbours {
+		count += int(neighbour)
+	}
+	if state == 1 && count >= rule.SurvivalMin && count <= rule.SurvivalMax {
+		return 1
+	}
+	if state == 0 && count >= rule.BirthMin && count <= rule.BirthMax {
+		return 1
+	}
+	return 0
+}
+
+// check that a 3D rule gets a 3D world and the world can be run in 3D
+func checkVolume(p Params, rule Rule) error {
+	_, bays := rule.(BaysRule)
+	switch {
+	case bays && p.ImageDepth <= 0:
+		return fmt.Errorf("rule %v needs a 3D world with ImageDepth set", rule)
+	case !bays && p.ImageDepth > 0:
+		return fmt.Errorf("a 3D world needs a 3D rule such as 4555, not %v", rule)
+	case !bays:
+		return nil
+	case p.Engine != StripEngine:
+		return fmt.Errorf("the %v engine does not support 3D worlds", p.Engine)
+	case p.Boundary != Torus && p.Boundary != DeadEdges:
+		return fmt.Errorf("a 3D world can only have a torus or dead edges, not %v", p.Boundary)
+	case p.Stochastic || p.Colour:
+		return fmt.Errorf("a 3D world cannot be stochastic or in colour")
+	}
+	return nil
+}
+
+// get the number of rows of the world, which holds the layers of a 3D world one after another
+func worldRows(p Params) int {
+	if p.ImageDepth > 0 {
+		return p.ImageHeight * p.ImageDepth
+	}
+	return p.ImageHeight
+}
+
+// get the name of the images of a world, e.g. 64x64, or 64x64x16 for the slices of a 3D world
+func imageName(p Params) string {
+	name := strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(p.ImageHeight)
+	if p.ImageDepth > 0 {
+		name += "x" + strconv.Itoa(p.ImageDepth)
+	}
+	return name
+}
+
+// volumeEngine runs a 3D world of ImageDepth layers, split into slabs of whole layers between a pool of workers
+// the same way the strip engine splits rows. The workers only read the layers of the last generation,
+// which are shared between them, so nothing needs to be exchanged between turns.
+// In the world and in events the layers come one after another, so row y of layer z is row z*ImageHeight+y.
+type volumeEngine struct {
+	p         Params
+	rule      Rule
+	cur, next [][][]byte // [z][y][x]
+	// around[d][i] are the positions before, at and after position i along dimension d (x, y then z),
+	// or -1 where they are beyond dead edges
+	around [3][][3]int
+	slabs  []HorSlice
+	work   []chan int
+	done   chan bool
+	c      distributorChannels
+}
+
+func newVolumeEngine(p Params, world [][]byte, rule Rule, c distributorChannels) *volumeEngine {
+	threads := p.Threads
+	if threads > p.ImageDepth {
+		threads = p.ImageDepth
+	}
+	e := &volumeEngine{
+		p:     p,
+		rule:  rule,
+		cur:   make([][][]byte, p.ImageDepth),
+		next:  make([][][]byte, p.ImageDepth),
+		slabs: splitRows(p.ImageDepth, threads),
+		work:  make([]chan int, threads),
+		done:  make(chan bool),
+		c:     c,
+	}
+	states := greyStates(2)
+	for z := range e.cur {
+		e.cur[z] = createNewSlice(p.ImageHeight, p.ImageWidth)
+		e.next[z] = createNewSlice(p.ImageHeight, p.ImageWidth)
+		for y, row := range world[z*p.ImageHeight : (z+1)*p.ImageHeight] {
+			for x, grey := range row {
+				e.cur[z][y][x] = states[grey]
+			}
+		}
+	}
+	for d, size := range []int{p.ImageWidth, p.ImageHeight, p.ImageDepth} {
+		e.around[d] = make([][3]int, size)
+		for i := range e.around[d] {
+			for k := range e.around[d][i] {
+				position := i + k - 1
+				if p.Boundary == DeadEdges && (position < 0 || position >= size) {
+					e.around[d][i][k] = -1
+				} else {
+					e.around[d][i][k] = wrapIndex(position, size)
+				}
+			}
+		}
+	}
+	for id, slab := range e.slabs {
+		e.work[id] = make(chan int)
+		go func(slab HorSlice, work chan int) {
+			for turn := range work {
+				e.evolve(slab, turn)
+				e.done <- true
+			}
+		}(slab, e.work[id])
+	}
+	return e
+}
+
+// compute the next generation of the layers of a slab into the next buffer
+func (e *volumeEngine) evolve(slab HorSlice, turn int) {
+	flips := newFlipSender(e.p, e.c, turn)
+	neighbours := make([]byte, 0, 26)
+	for z := slab.startRow; z < slab.endRow; z++ {
+		for y := 0; y < e.p.ImageHeight; y++ {
+			for x := 0; x < e.p.ImageWidth; x++ {
+				neighbours = neighbours[:0]
+				for dz, nz := range e.around[2][z] {
+					for dy, ny := range e.around[1][y] {
+						for dx, nx := range e.around[0][x] {
+							if dz == 1 && dy == 1 && dx == 1 {
+								continue
+							}
+							if nz < 0 || ny < 0 || nx < 0 {
+								neighbours = append(neighbours, 0)
+							} else {
+								neighbours = append(neighbours, e.cur[nz][ny][nx])
+							}
+						}
+					}
+				}
+				state := e.cur[z][y][x]
+				updatedCell := e.rule.Next(state, neighbours)
+				e.next[z][y][x] = updatedCell
+				if updatedCell != state {
+					flips.change(util.Cell{X: x, Y: z*e.p.ImageHeight + y}, state, updatedCell)
+				}
+			}
+		}
+	}
+	flips.send()
+}
+
+// start the turn on every slab and wait for them all to finish it
+func (e *volumeEngine) step(turn, turns int) int {
+	for _, work := range e.work {
+		work <- turn
+	}
+	for range e.work {
+		<-e.done
+	}
+	e.cur, e.next = e.next, e.cur
+	return 1
+}
+
+// get the layers of the world one after another
+func (e *volumeEngine) world() [][]byte {
+	world := createNewSlice(worldRows(e.p), e.p.ImageWidth)
+	for z, layer := range e.cur {
+		for y, row := range layer {
+			for x, state := range row {
+				if state == 1 {
+					world[z*e.p.ImageHeight+y][x] = 0xFF
+				}
+			}
+		}
+	}
+	return world
+}
+
+func (e *volumeEngine) aliveCount() int {
+	return getAliveCellsCount(e.world())
+}
+
+func (e *volumeEngine) aliveCells() []util.Cell {
+	return getAliveCells(e.world())
+}
+
+func (e *volumeEngine) stop() {
+	for _, work := range e.work {
+		close(work)
+	}
+}
@@ -0,0 +1,243 @@
+package gol
+
+import (
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// a cell beyond the edges of a strip that its worker reads, kept in the halo around the worker's buffer
+type haloCell struct {
+	row, column int       // position in the buffer of the worker reading it
+	source      util.Cell // position in the world, in the strip of the worker sending it
+}
+
+// worker owns one strip of the world for the whole run and evolves it in place, holding the state of each cell.
+// Its two buffers hold the strip surrounded by a halo of the cells it reads beyond its edges:
+// radius rows above and below and radius columns either side, wherever the topology puts them.
+// Row i, column j of a buffer is the cell in row startRow+i-radius, column j-radius of the world.
+// After each turn the workers send each other the halo cells they need, usually rows from the workers above and below.
+type worker struct {
+	id        int
+	slice     HorSlice
+	radius    int
+	cur, next [][]byte
+	// sums[i][j] is the number of alive cells before column j of row i of cur, used to count with a spanRule
+	sums [][]int
+	// sends[to] are the cells of this strip needed by worker to, in the order it expects them
+	sends [][]haloCell
+	// receives[from] are the halo cells sent by worker from
+	receives [][]haloCell
+	out      []chan<- []byte // out[to] carries halo cells to worker to
+	in       []<-chan []byte // in[from] carries halo cells from worker from
+	work     chan int
+	done     chan<- bool
+}
+
+func newWorker(id int, slice HorSlice, radius, width, threads int, done chan<- bool) *worker {
+	rows := slice.endRow - slice.startRow
+	return &worker{
+		id:       id,
+		slice:    slice,
+		radius:   radius,
+		cur:      createNewSlice(rows+2*radius, width+2*radius),
+		next:     createNewSlice(rows+2*radius, width+2*radius),
+		sends:    make([][]haloCell, threads),
+		receives: make([][]haloCell, threads),
+		out:      make([]chan<- []byte, threads),
+		in:       make([]<-chan []byte, threads),
+		work:     make(chan int),
+		done:     done,
+	}
+}
+
+// start a worker for every strip, loading the states of the world into their buffers
+// and working out who sends which halo cells
+func startWorkers(p Params, world [][]byte, rule Rule, t topology, tiles *tileActivity, c distributorChannels, done chan<- bool) []*worker {
+	radius := neighbourhoodRadius(rule.Neighbourhood())
+	strips := splitRows(p.ImageHeight, p.Threads)
+	workers := make([]*worker, p.Threads)
+	for id, slice := range strips {
+		workers[id] = newWorker(id, slice, radius, p.ImageWidth, p.Threads, done)
+		for i := slice.startRow; i < slice.endRow; i++ {
+			copy(workers[id].cur[i-slice.startRow+radius][radius:], world[i])
+		}
+	}
+
+	for _, w := range workers {
+		for from, cells := range haloCells(t, strips, radius, w.id) {
+			w.receives[from] = cells
+			workers[from].sends[w.id] = cells
+			for _, h := range cells {
+				w.cur[h.row][h.column] = world[h.source.Y][h.source.X]
+			}
+		}
+	}
+
+	for _, from := range workers {
+		for to, cells := range from.sends {
+			if to != from.id && len(cells) > 0 {
+				// one message per turn, which is always received before the next turn starts
+				halo := make(chan []byte, 1)
+				from.out[to] = halo
+				workers[to].in[from.id] = halo
+			}
+		}
+	}
+
+	for _, w := range workers {
+		go w.run(p, rule, tiles, c)
+	}
+	return workers
+}
+
+// work out the halo cells of the buffer of strip id, wherever the topology puts them.
+// The cells read from each strip are in the order they are sent in, e.g. halo[from] comes from strip from.
+func haloCells(t topology, strips []HorSlice, radius, id int) [][]haloCell {
+	owner := make([]int, t.height)
+	for id, slice := range strips {
+		for i := slice.startRow; i < slice.endRow; i++ {
+			owner[i] = id
+		}
+	}
+	halo := make([][]haloCell, len(strips))
+	slice := strips[id]
+	rows := slice.endRow - slice.startRow
+	for i := 0; i < rows+2*radius; i++ {
+		for j := 0; j < t.width+2*radius; j++ {
+			if i >= radius && i < rows+radius && j >= radius && j < t.width+radius {
+				continue
+			}
+			row, column, ok := t.wrap(slice.startRow+i-radius, j-radius)
+			if !ok {
+				// permanently dead, so left as 0 in both buffers
+				continue
+			}
+			from := owner[row]
+			halo[from] = append(halo[from], haloCell{row: i, column: j, source: util.Cell{X: column, Y: row}})
+		}
+	}
+	return halo
+}
+
+// evolve the strip every time a turn is sent, until the work channel is closed
+func (w *worker) run(p Params, rule Rule, tiles *tileActivity, c distributorChannels) {
+	kernel := newRowKernel(p.Kernel, rule)
+	for turn := range w.work {
+		w.evolve(p, rule, kernel, tiles, c, turn)
+		w.cur, w.next = w.next, w.cur
+		w.exchange()
+		w.done <- true
+	}
+}
+
+// compute the next generation of the strip into the next buffer, copying the tiles that are not active.
+// Each run of active tiles along a row is computed by the kernel in one go.
+// Cells are flipped when they become alive or stop being alive, or every change is sent when Params.Colour is set.
+// A rule giving a state it does not have, which only a Params.Automaton from outside this package can, stops the run.
+func (w *worker) evolve(p Params, rule Rule, kernel rowKernel, tiles *tileActivity, c distributorChannels, turn int) {
+	flips := newStateSender(p, c, turn, rule)
+	states := rule.States()
+	kernel.begin(w)
+	for i := w.slice.startRow; i < w.slice.endRow; i++ {
+		bi := i - w.slice.startRow + w.radius
+		active := tiles.active[i/tileSize]
+		for tc := 0; tc < tiles.columns; {
+			start := tc * tileSize
+			last := tc + 1
+			for last < tiles.columns && active[last] == active[tc] {
+				last++
+			}
+			end := last * tileSize
+			if end > p.ImageWidth {
+				end = p.ImageWidth
+			}
+			for t := tc; t < last; t++ {
+				tiles.changed[i][t] = false
+			}
+			if !active[tc] {
+				copy(w.next[bi][start+w.radius:end+w.radius], w.cur[bi][start+w.radius:end+w.radius])
+				tc = last
+				continue
+			}
+			// get new states for the cells and put them in the next buffer
+			kernel.row(w, bi, start, end)
+			for j := start; j < end; j++ {
+				bj := j + w.radius
+				state, updatedCell := w.cur[bi][bj], w.next[bi][bj]
+				if p.Stochastic {
+					updatedCell = stochasticNext(p, states, turn, j, i, state, updatedCell)
+					w.next[bi][bj] = updatedCell
+				}
+				if int(updatedCell) >= states {
+					panic(fmt.Sprintf("rule %v gave cell (%d, %d) state %d, but only has %d states", rule, j, i, updatedCell, states))
+				}
+				if updatedCell != state {
+					tiles.changed[i][j/tileSize] = true
+					// cellFlipped event
+					flips.change(util.Cell{X: j, Y: i}, state, updatedCell)
+				}
+			}
+			tc = last
+		}
+	}
+	flips.send()
+}
+
+// work out the running sums of alive cells along each row of cur, halo included
+func (w *worker) sumRows() {
+	if w.sums == nil {
+		w.sums = make([][]int, len(w.cur))
+		for i := range w.sums {
+			w.sums[i] = make([]int, len(w.cur[i])+1)
+		}
+	}
+	for i, row := range w.cur {
+		sums := w.sums[i]
+		for j, state := range row {
+			sums[j+1] = sums[j]
+			if state == 1 {
+				sums[j+1]++
+			}
+		}
+	}
+}
+
+// send the cells other workers need from this strip and fill the halo with the cells they send back
+func (w *worker) exchange() {
+	for to, cells := range w.sends {
+		if to == w.id {
+			continue
+		}
+		if len(cells) > 0 {
+			w.out[to] <- w.values(cells)
+		}
+	}
+	// the strip may wrap around onto itself, e.g. the left and right columns or a single worker
+	for _, h := range w.receives[w.id] {
+		w.cur[h.row][h.column] = w.at(h.source)
+	}
+	for from, cells := range w.receives {
+		if from == w.id || len(cells) == 0 {
+			continue
+		}
+		values := <-w.in[from]
+		for k, h := range cells {
+			w.cur[h.row][h.column] = values[k]
+		}
+	}
+}
+
+// get the values of cells in this strip
+func (w *worker) values(cells []haloCell) []byte {
+	values := make([]byte, len(cells))
+	for k, h := range cells {
+		values[k] = w.at(h.source)
+	}
+	return values
+}
+
+// get the cell at a position in the world, which must be in this strip
+func (w *worker) at(cell util.Cell) byte {
+	return w.cur[cell.Y-w.slice.startRow+w.radius][cell.X+w.radius]
+}
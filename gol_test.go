@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"strconv"
@@ -11,16 +12,26 @@ import (
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
-// TestGol tests 16x16, 64x64 and 512x512 images on 0, 1 and 100 turns using 1-16 worker threads.
+// run TestGol on the workers of a broker, e.g. go test -run TestGol -broker 127.0.0.1:8030
+var brokerAddress = flag.String("broker", "", "Specify the address of a broker to run TestGol on. Defaults to running it locally.")
+
+// TestGol tests 16x16, 64x64, 512x512, 15x17 and 1000x600 images on 0, 1 and 100 turns using 1-16 worker threads.
 func TestGol(t *testing.T) {
+	testGol(t, *brokerAddress)
+}
+
+// run the TestGol images, on the broker at the given address if there is one
+func testGol(t *testing.T, broker string) {
 	tests := []gol.Params{
 		{ImageWidth: 16, ImageHeight: 16},
 		{ImageWidth: 64, ImageHeight: 64},
 		{ImageWidth: 512, ImageHeight: 512},
+		{ImageWidth: 15, ImageHeight: 17},
+		{ImageWidth: 1000, ImageHeight: 600},
 	}
 	for _, p := range tests {
 		for _, turns := range []int{0, 1, 100} {
-			p.Turns = turns
+			p.Turns, p.Broker = turns, broker
 			expectedAlive := readAliveCells(
 				"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
 				p.ImageWidth,
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestHashLife tests the HashLife engine against the TestGol images and two non-Conway rules.
+func TestHashLife(t *testing.T) {
+	type hashLifeTest struct {
+		p     gol.Params
+		check string
+	}
+	var tests []hashLifeTest
+	for _, p := range []gol.Params{
+		{ImageWidth: 16, ImageHeight: 16},
+		{ImageWidth: 64, ImageHeight: 64},
+		{ImageWidth: 512, ImageHeight: 512},
+	} {
+		for _, turns := range []int{0, 1, 100} {
+			p.Turns = turns
+			tests = append(tests, hashLifeTest{p, "check/images"})
+		}
+	}
+	for _, rule := range []string{"B36/S23", "B3678/S34678"} {
+		for _, turns := range []int{1, 10, 100} {
+			p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: turns, Rule: rule}
+			tests = append(tests, hashLifeTest{p, "check/rules/" + strings.Replace(rule, "/", "", 1)})
+		}
+	}
+
+	for _, test := range tests {
+		p := test.p
+		p.Engine = gol.HashLifeEngine
+		p.Threads = 1
+		expectedAlive := readAliveCells(
+			test.check+fmt.Sprintf("/%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+			p.ImageWidth,
+			p.ImageHeight,
+		)
+		testName := fmt.Sprintf("%s-%dx%dx%d", test.check, p.ImageWidth, p.ImageHeight, p.Turns)
+		t.Run(testName, func(t *testing.T) {
+			assertEqualBoard(t, runFinal(p), expectedAlive, p)
+		})
+	}
+}
+
+// TestHashLifeLong checks the 512x512 alive counts after billions of turns, after thousands with a tiny cache
+// that forces frequent garbage collection, and after hundreds with a cache too small for even one generation.
+func TestHashLifeLong(t *testing.T) {
+	alive := readAliveCounts(512, 512)
+	tests := []struct {
+		turns, nodes, expected int
+	}{
+		{turns: 1000000000, expected: 5565},
+		{turns: 10000000001, expected: 5567},
+		{turns: 3000, nodes: 1 << 18, expected: alive[3000]},
+		{turns: 12345, nodes: 1 << 18, expected: 5567},
+		{turns: 100, nodes: 1 << 10, expected: alive[100]},
+	}
+	for _, test := range tests {
+		p := gol.Params{
+			Turns:         test.turns,
+			Threads:       1,
+			ImageWidth:    512,
+			ImageHeight:   512,
+			Engine:        gol.HashLifeEngine,
+			HashLifeNodes: test.nodes,
+		}
+		t.Run(fmt.Sprintf("512x512x%d-%d", test.turns, test.nodes), func(t *testing.T) {
+			if actual := len(runFinal(p)); actual != test.expected {
+				t.Errorf("At turn %v expected %v alive cells, got %v instead", p.Turns, test.expected, actual)
+			}
+		})
+	}
+}
+
+// run the params to completion and get the final alive cells
+func runFinal(p gol.Params) []util.Cell {
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+	return cells
+}
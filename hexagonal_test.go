@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestHexagonal tests B2/S34H and a hexagonal Larger than Life rule for 1, 10 and 100 turns.
+// The output PGM and the alive cells must match the images in check/hex.
+func TestHexagonal(t *testing.T) {
+	tests := []struct {
+		rule    string
+		check   string
+		size    util.Cell
+		threads []int
+	}{
+		{"B2/S34H", "check/hex/B2S34H", util.Cell{X: 64, Y: 64}, []int{1, 2, 3, 5, 8, 16}},
+		{"B2/S34H", "check/hex/B2S34H", util.Cell{X: 15, Y: 17}, []int{1, 2, 5, 8, 16}},
+		{"R2,C0,M0,S3..6,B4..5,NH", "check/hex/ltl", util.Cell{X: 256, Y: 256}, []int{1, 3, 8, 16}},
+		{"R2,C0,M0,S3..6,B4..5,NH", "check/hex/ltl", util.Cell{X: 15, Y: 17}, []int{1, 2, 5, 8, 16}},
+	}
+	for _, test := range tests {
+		for _, turns := range []int{1, 10, 100} {
+			p := gol.Params{ImageWidth: test.size.X, ImageHeight: test.size.Y, Turns: turns, Rule: test.rule}
+			path := fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns)
+			expected, err := ioutil.ReadFile(test.check + "/" + path)
+			util.Check(err)
+			expectedAlive := readAliveCells(test.check+"/"+path, p.ImageWidth, p.ImageHeight)
+			for _, threads := range test.threads {
+				p.Threads = threads
+				testName := fmt.Sprintf("%v-%dx%dx%d-%d", test.rule, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+				t.Run(testName, func(t *testing.T) {
+					assertEqualBoard(t, runFinal(p), expectedAlive, p)
+					output, err := ioutil.ReadFile("out/" + path)
+					util.Check(err)
+					if !bytes.Equal(output, expected) {
+						t.Errorf("out/%v does not match %v/%v", path, test.check, path)
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestParseHexRule tests parsing hexagonal B/S rulestrings, and the sizes of hexagonal neighbourhoods.
+func TestParseHexRule(t *testing.T) {
+	valid := map[string]string{
+		"B2/S34H":  "B2/S34H",
+		"b2/s34h":  "B2/S34H",
+		"34/2H":    "B2/S34H",
+		"B246/S3H": "B246/S3H",
+	}
+	for rulestring, expected := range valid {
+		rule, err := gol.ParseRulestring(rulestring)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", rulestring, err)
+		} else if fmt.Sprint(rule) != expected {
+			t.Errorf("%q parsed as %v, should be %v", rulestring, rule, expected)
+		}
+	}
+	for _, rulestring := range []string{"B7/S34H", "B2/S38H", "B2/S34HH", "B2/S3xH"} {
+		if _, err := gol.ParseRulestring(rulestring); err == nil {
+			t.Errorf("%q: expected an error", rulestring)
+		}
+	}
+
+	for i, expected := range []int{6, 18, 36, 90} {
+		radius := []int{1, 2, 3, 5}[i]
+		if size := len(gol.Hexagonal.Neighbourhood(radius)); size != expected {
+			t.Errorf("hexagonal neighbourhood of radius %d has %d cells, should be %d", radius, size, expected)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestIsotropic tests spaceships and oscillators of isotropic non-totalistic rules, each alone in an image.
+// After a full period the pattern must be back, moved by its displacement, and it must not match itself any sooner.
+// tlife keeps Life's glider and turns the T-tetromino into a c/5 spaceship,
+// and Just Friends has a 3 cell c/6 diagonal spaceship and a 4 cell period 14 oscillator.
+func TestIsotropic(t *testing.T) {
+	tests := []struct {
+		name         string
+		rule         string
+		size         int
+		period       int
+		displacement util.Cell
+	}{
+		{"tlife T-tetromino", "B3/S2-i34q", 20, 5, util.Cell{X: 0, Y: 1}},
+		{"tlife glider", "B3/S2-i34q", 21, 4, util.Cell{X: 1, Y: 1}},
+		{"Just Friends spaceship", "B2-a/S12", 22, 6, util.Cell{X: 1, Y: 1}},
+		{"Just Friends p14", "B2-a/S12", 23, 14, util.Cell{}},
+	}
+	for _, test := range tests {
+		initial := readAliveCells(fmt.Sprintf("images/%vx%v.pgm", test.size, test.size), test.size, test.size)
+		var expectedAlive []util.Cell
+		for _, cell := range initial {
+			expectedAlive = append(expectedAlive, util.Cell{
+				X: (cell.X + test.displacement.X + test.size) % test.size,
+				Y: (cell.Y + test.displacement.Y + test.size) % test.size,
+			})
+		}
+		for _, threads := range []int{1, 3, 8} {
+			for turns := 1; turns <= test.period; turns++ {
+				p := gol.Params{ImageWidth: test.size, ImageHeight: test.size, Turns: turns, Threads: threads, Rule: test.rule}
+				testName := fmt.Sprintf("%v-%v-%d-%d", test.name, test.rule, p.Turns, p.Threads)
+				t.Run(testName, func(t *testing.T) {
+					alive := runFinal(p)
+					if turns == test.period {
+						assertEqualBoard(t, alive, expectedAlive, p)
+					} else if reflect.DeepEqual(normaliseCells(alive), normaliseCells(initial)) {
+						t.Errorf("pattern repeats after %d turns, should be %d", turns, test.period)
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestParseIsotropic tests parsing rulestrings in Hensel notation, and that a rulestring listing every letter
+// gives the same result as Conway's rule for every arrangement of neighbours.
+func TestParseIsotropic(t *testing.T) {
+	valid := map[string]string{
+		"B2-a/S12":                         "B2-a/S12",
+		"b3/s2-i34q":                       "B3/S2-i34q",
+		"S12/B2-a":                         "B2-a/S12",
+		"B2cek/S":                          "B2cek/S",
+		"B2ceaik/S1e":                      "B2-n/S1e",
+		"B3ceaiknjqry/S2ceaikn3ceaiknjqry": "B3/S23",
+		"B5-k6e/S7c":                       "B5-k6e/S7c",
+	}
+	for rulestring, expected := range valid {
+		rule, err := gol.ParseRulestring(rulestring)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", rulestring, err)
+		} else if _, ok := rule.(gol.IsotropicRule); !ok {
+			t.Errorf("%q parsed as a %T, should be an IsotropicRule", rulestring, rule)
+		} else if fmt.Sprint(rule) != expected {
+			t.Errorf("%q parsed as %v, should be %v", rulestring, rule, expected)
+		}
+	}
+	invalid := []string{
+		"B2x/S12",
+		"B2-/S1",
+		"B2aa/S1",
+		"B1a/S1",
+		"B2a2e/S1",
+		"B0c/S1",
+		"B2a/Q1",
+		"B2a",
+	}
+	for _, rulestring := range invalid {
+		if _, err := gol.ParseIsotropic(rulestring); err == nil {
+			t.Errorf("%q: expected an error", rulestring)
+		}
+	}
+
+	life, err := gol.ParseIsotropic("B3ceaiknjqry/S2ceaikn3ceaiknjqry")
+	util.Check(err)
+	neighbours := make([]byte, 8)
+	for arrangement := 0; arrangement < 256; arrangement++ {
+		for k := range neighbours {
+			neighbours[k] = byte(arrangement >> uint(k) & 1)
+		}
+		for state := byte(0); state <= 1; state++ {
+			if next, expected := life.Next(state, neighbours), gol.Conway.Next(state, neighbours); next != expected {
+				t.Errorf("state %d with neighbours %v becomes %d, should be %d", state, neighbours, next, expected)
+			}
+		}
+	}
+}
+
+// move cells so that the top left of their bounding box is at 0,0 and sort them
+func normaliseCells(cells []util.Cell) []util.Cell {
+	if len(cells) == 0 {
+		return cells
+	}
+	origin := cells[0]
+	for _, cell := range cells {
+		origin = util.Cell{X: minInt(origin.X, cell.X), Y: minInt(origin.Y, cell.Y)}
+	}
+	normalised := make([]util.Cell, len(cells))
+	for i, cell := range cells {
+		normalised[i] = util.Cell{X: cell.X - origin.X, Y: cell.Y - origin.Y}
+	}
+	sort.Slice(normalised, func(i, j int) bool {
+		if normalised[i].Y != normalised[j].Y {
+			return normalised[i].Y < normalised[j].Y
+		}
+		return normalised[i].X < normalised[j].X
+	})
+	return normalised
+}
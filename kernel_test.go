@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestKernels tests every kernel a rule supports against the images in check, using 1-16 worker threads.
+// The output PGM, with the state of every cell, must match whatever runs of tiles the kernels are given.
+func TestKernels(t *testing.T) {
+	tests := []struct {
+		rule    string
+		check   string
+		size    util.Cell
+		kernels []gol.Kernel
+	}{
+		{"B3/S23", "check/images", util.Cell{X: 64, Y: 64}, []gol.Kernel{gol.NeighbourKernel, gol.SumKernel, gol.TableKernel}},
+		{"B3/S23", "check/images", util.Cell{X: 15, Y: 17}, []gol.Kernel{gol.NeighbourKernel, gol.SumKernel, gol.TableKernel}},
+		{"B2/S34H", "check/hex/B2S34H", util.Cell{X: 64, Y: 64}, []gol.Kernel{gol.NeighbourKernel, gol.SumKernel, gol.TableKernel}},
+		{"R5,C0,M1,S34..58,B34..45,NM", "check/ltl/bosco", util.Cell{X: 256, Y: 256}, []gol.Kernel{gol.NeighbourKernel, gol.SumKernel}},
+		{"R3,C0,M0,S4..9,B5..8,NN", "check/ltl/vonneumann", util.Cell{X: 15, Y: 17}, []gol.Kernel{gol.NeighbourKernel, gol.SumKernel}},
+		{"R2,C3,M0,S5..8,B4..5,NM", "check/ltl/generations", util.Cell{X: 16, Y: 16}, []gol.Kernel{gol.NeighbourKernel, gol.SumKernel}},
+		{"B2/S/C3", "check/generations/B2SC3", util.Cell{X: 64, Y: 64}, []gol.Kernel{gol.NeighbourKernel}},
+	}
+	for _, test := range tests {
+		for _, turns := range []int{1, 100} {
+			p := gol.Params{ImageWidth: test.size.X, ImageHeight: test.size.Y, Turns: turns, Rule: test.rule}
+			path := fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns)
+			expected, err := ioutil.ReadFile(test.check + "/" + path)
+			util.Check(err)
+			for _, kernel := range test.kernels {
+				for _, threads := range []int{1, 3, 16} {
+					p.Kernel, p.Threads = kernel, threads
+					testName := fmt.Sprintf("%v-%v-%dx%dx%d-%d", test.rule, kernel, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+					t.Run(testName, func(t *testing.T) {
+						runFinal(p)
+						output, err := ioutil.ReadFile("out/" + path)
+						util.Check(err)
+						if !bytes.Equal(output, expected) {
+							t.Errorf("out/%v does not match %v/%v", path, test.check, path)
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+// TestParseKernel tests the names of the kernels.
+func TestParseKernel(t *testing.T) {
+	for name, expected := range map[string]gol.Kernel{"auto": gol.AutoKernel, "neighbour": gol.NeighbourKernel, "sum": gol.SumKernel, "table": gol.TableKernel} {
+		if kernel, err := gol.ParseKernel(name); err != nil || kernel != expected {
+			t.Errorf("%q parsed as %v, %v, should be %v", name, kernel, err, expected)
+		}
+	}
+	if _, err := gol.ParseKernel("bit"); err == nil {
+		t.Errorf("%q: expected an error", "bit")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestLargerThanLife tests Larger than Life rules with each neighbourhood shape, and one with dying states,
+// for 1, 10 and 100 turns. The output PGM and the alive cells must match the images in check/ltl.
+func TestLargerThanLife(t *testing.T) {
+	tests := []struct {
+		rule    string
+		check   string
+		size    util.Cell
+		threads []int
+	}{
+		{"R5,C0,M1,S34..58,B34..45,NM", "check/ltl/bosco", util.Cell{X: 256, Y: 256}, []int{1, 3, 8, 16}},
+		{"R3,C0,M0,S4..9,B5..8,NN", "check/ltl/vonneumann", util.Cell{X: 256, Y: 256}, []int{1, 3, 8, 16}},
+		{"R3,C0,M0,S4..9,B5..8,NN", "check/ltl/vonneumann", util.Cell{X: 15, Y: 17}, []int{1, 2, 5, 8, 16}},
+		{"R4,C0,M1,S30..55,B29..40,NC", "check/ltl/circular", util.Cell{X: 128, Y: 128}, []int{1, 3, 8, 16}},
+		{"R2,C3,M0,S5..8,B4..5,NM", "check/ltl/generations", util.Cell{X: 15, Y: 17}, []int{1, 2, 5, 8, 16}},
+		{"R2,C3,M0,S5..8,B4..5,NM", "check/ltl/generations", util.Cell{X: 16, Y: 16}, []int{1, 2, 5, 8, 16}},
+	}
+	for _, test := range tests {
+		rule, err := gol.ParseLargerThanLife(test.rule)
+		util.Check(err)
+		for _, turns := range []int{1, 10, 100} {
+			p := gol.Params{ImageWidth: test.size.X, ImageHeight: test.size.Y, Turns: turns, Rule: test.rule}
+			path := fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns)
+			expected, err := ioutil.ReadFile(test.check + "/" + path)
+			util.Check(err)
+			var expectedAlive []util.Cell
+			for y, row := range greysToStates(expected[len(expected)-p.ImageWidth*p.ImageHeight:], rule.States(), p.ImageWidth) {
+				for x, state := range row {
+					if state == 1 {
+						expectedAlive = append(expectedAlive, util.Cell{X: x, Y: y})
+					}
+				}
+			}
+			for _, threads := range test.threads {
+				p.Threads = threads
+				testName := fmt.Sprintf("%v-%dx%dx%d-%d", test.rule, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+				t.Run(testName, func(t *testing.T) {
+					assertEqualBoard(t, runFinal(p), expectedAlive, p)
+					output, err := ioutil.ReadFile("out/" + path)
+					util.Check(err)
+					if !bytes.Equal(output, expected) {
+						t.Errorf("out/%v does not match %v/%v", path, test.check, path)
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestBosco tests the ready made Bosco's Rule against the Larger than Life rulestring it stands for.
+func TestBosco(t *testing.T) {
+	p := gol.Params{ImageWidth: 256, ImageHeight: 256, Turns: 100, Threads: 8, Automaton: gol.Bosco}
+	expectedAlive := readAliveCells("check/ltl/bosco/256x256x100.pgm", p.ImageWidth, p.ImageHeight)
+	assertEqualBoard(t, runFinal(p), expectedAlive, p)
+}
+
+// TestParseLargerThanLife tests parsing rulestrings in Golly's notation, and the sizes of the neighbourhood shapes.
+func TestParseLargerThanLife(t *testing.T) {
+	valid := map[string]string{
+		"R5,C0,M1,S34..58,B34..45,NM": "R5,C0,M1,S34..58,B34..45,NM",
+		"r3,c2,m0,s4..9,b5..8,nn":     "R3,C0,M0,S4..9,B5..8,NN",
+		"R2,C3,S5..8,B4":              "R2,C3,M0,S5..8,B4..4,NM",
+		"R10,M1,S0..120,B60..90,NC":   "R10,C0,M1,S0..120,B60..90,NC",
+	}
+	for rulestring, expected := range valid {
+		rule, err := gol.ParseRulestring(rulestring)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", rulestring, err)
+		} else if fmt.Sprint(rule) != expected {
+			t.Errorf("%q parsed as %v, should be %v", rulestring, rule, expected)
+		}
+	}
+	invalid := []string{
+		"R0,S1,B1",
+		"R5,C1,S1,B1",
+		"R5,M2,S1,B1",
+		"R5,S5..4,B1",
+		"R5,S1,B1,NX",
+		"R5,S1",
+		"R5,,S1,B1",
+		"R5,S1,B1,Q3",
+	}
+	for _, rulestring := range invalid {
+		if _, err := gol.ParseRulestring(rulestring); err == nil {
+			t.Errorf("%q: expected an error", rulestring)
+		}
+	}
+
+	sizes := map[gol.Shape][]int{
+		gol.Moore:      {8, 24, 48, 120},
+		gol.VonNeumann: {4, 12, 24, 60},
+		gol.Circular:   {8, 20, 36, 96},
+	}
+	for shape, expected := range sizes {
+		for i, radius := range []int{1, 2, 3, 5} {
+			if size := len(shape.Neighbourhood(radius)); size != expected[i] {
+				t.Errorf("%v neighbourhood of radius %d has %d cells, should be %d", shape, radius, size, expected[i])
+			}
+		}
+	}
+}
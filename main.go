@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"runtime"
 
 	"uk.ac.bris.cs/gameoflife/gol"
@@ -32,12 +33,98 @@ func main() {
 		512,
 		"Specify the height of the image. Defaults to 512.")
 
+	flag.IntVar(
+		&params.ImageDepth,
+		"d",
+		0,
+		"Specify the number of layers of a 3D world, read from a directory of PGM slices, for 3D rules such as 4555. Defaults to 0, a 2D world.")
+
 	flag.IntVar(
 		&params.Turns,
 		"turns",
 		10000000000,
 		"Specify the number of turns to process. Defaults to 10000000000.")
 
+	flag.StringVar(
+		&params.Rule,
+		"rule",
+		gol.ConwayRule,
+		"Specify the B/S, B/S/C, hexagonal, Hensel, Larger than Life, 1D or 3D rulestring or the name of a rule to simulate, e.g. B36/S23, B2/S/C3, B2/S34H, B2-a/S12, R5,C0,M1,S34..58,B34..45,NM, W30, 4555, Wireworld or QuadLife. Defaults to B3/S23.")
+
+	boundary := flag.String(
+		"boundary",
+		"torus",
+		"Specify how the edges of the world are joined: torus, dead, klein, cross or twisted. Defaults to torus.")
+
+	flag.IntVar(
+		&params.Shift,
+		"shift",
+		0,
+		"Specify the horizontal shift of the top/bottom edges of a twisted torus. Defaults to 0.")
+
+	engine := flag.String(
+		"engine",
+		"strip",
+		"Specify the engine used to compute generations: strip, bit, hashlife or sparse (unbounded). Defaults to strip.")
+
+	kernel := flag.String(
+		"kernel",
+		"auto",
+		"Specify how the strip engine computes each cell: auto, neighbour, sum or table. Defaults to auto.")
+
+	flag.BoolVar(
+		&params.BatchFlips,
+		"batch",
+		true,
+		"Send the cells flipped by each worker in a turn as one event. Defaults to true.")
+
+	flag.BoolVar(
+		&params.Colour,
+		"colour",
+		false,
+		"Read and write PPM images and show every state in colour, e.g. with -rule Wireworld or QuadLife. Defaults to false.")
+
+	flag.Float64Var(
+		&params.BirthProbability,
+		"birth",
+		1,
+		"Specify the probability that a cell the rule gives birth to is born. Defaults to 1.")
+
+	flag.Float64Var(
+		&params.DeathProbability,
+		"death",
+		0,
+		"Specify the probability that a cell the rule keeps alive dies anyway. Defaults to 0.")
+
+	flag.Int64Var(
+		&params.Seed,
+		"seed",
+		0,
+		"Specify the seed of the random births and deaths. Defaults to 0.")
+
+	flag.BoolVar(
+		&params.SingleCell,
+		"single",
+		false,
+		"Start a 1D rule such as W30 from a single alive cell instead of the first row of the image.")
+
+	hex := flag.Bool(
+		"hex",
+		false,
+		"Draw cells as staggered hexes, for hexagonal rules such as B2/S34H.")
+
+	flag.StringVar(
+		&params.Broker,
+		"broker",
+		"",
+		"Specify the address of a broker to run the turns on its workers, e.g. 127.0.0.1:8030. Defaults to running them locally.")
+
+	flag.BoolVar(
+		&params.Attach,
+		"attach",
+		false,
+		"Attach to the run already on the broker, left by another controller with 'q', instead of starting one from the image.")
+
 	noVis := flag.Bool(
 		"noVis",
 		false,
@@ -48,13 +135,54 @@ func main() {
 	fmt.Println("Threads:", params.Threads)
 	fmt.Println("Width:", params.ImageWidth)
 	fmt.Println("Height:", params.ImageHeight)
+	if params.ImageDepth > 0 {
+		fmt.Println("Depth:", params.ImageDepth)
+	}
+
+	rule, err := gol.ParseRulestring(params.Rule)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("Rule:", rule)
+
+	params.Boundary, err = gol.ParseBoundary(*boundary)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("Boundary:", params.Boundary)
+
+	params.Engine, err = gol.ParseEngine(*engine)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("Engine:", params.Engine)
+
+	params.Kernel, err = gol.ParseKernel(*kernel)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("Kernel:", params.Kernel)
+	if params.Broker != "" {
+		fmt.Println("Broker:", params.Broker)
+	}
+
+	// random births and deaths are only used when asked for
+	params.Stochastic = params.BirthProbability != 1 || params.DeathProbability != 0
 
 	keyPresses := make(chan rune, 10)
 	events := make(chan gol.Event, 1000)
 
 	go gol.Run(params, events, keyPresses)
 	if !(*noVis) {
-		sdl.Run(params, events, keyPresses)
+		display := sdl.Squares
+		if *hex {
+			display = sdl.Hexes
+		}
+		sdl.Run(params, events, keyPresses, display)
 	} else {
 		complete := false
 		for !complete {
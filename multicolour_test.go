@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestColourCellsCount tests that QuadLife has the same alive cells as Life from the same pattern, whatever their colours,
+// and that the last ColourCellsCount event, sent before FinalTurnComplete, counts the final state of every cell.
+func TestColourCellsCount(t *testing.T) {
+	for _, turns := range []int{0, 1, 100} {
+		life := gol.Params{ImageWidth: 48, ImageHeight: 48, Turns: turns, Threads: 4}
+		expectedAlive := runFinal(life)
+		for _, threads := range []int{1, 3, 8} {
+			p := gol.Params{ImageWidth: 48, ImageHeight: 48, Turns: turns, Threads: threads, Rule: "QuadLife", Colour: true}
+			t.Run(fmt.Sprintf("%dx%dx%d-%d", p.ImageWidth, p.ImageHeight, p.Turns, p.Threads), func(t *testing.T) {
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				var counts *gol.ColourCellsCount
+				var final gol.FinalTurnComplete
+				for event := range events {
+					switch e := event.(type) {
+					case gol.ColourCellsCount:
+						counts = &e
+					case gol.FinalTurnComplete:
+						if counts == nil {
+							t.Fatal("no ColourCellsCount event before FinalTurnComplete")
+						}
+						final = e
+					}
+				}
+				assertEqualBoard(t, final.Alive, expectedAlive, p)
+				expected := make([]int, 5)
+				for _, row := range final.States {
+					for _, state := range row {
+						expected[state]++
+					}
+				}
+				if fmt.Sprint(counts.CellsCounts) != fmt.Sprint(expected) {
+					t.Errorf("ColourCellsCount has %v cells of each colour, should be %v", counts.CellsCounts, expected)
+				}
+			})
+		}
+	}
+}
+
+// TestColourBirths tests the colour a cell is born with: the colour most of its parents have,
+// or in QuadLife the colour none of them have when all 3 differ.
+func TestColourBirths(t *testing.T) {
+	tests := []struct {
+		rule       gol.ColourRule
+		neighbours []byte
+		expected   byte
+	}{
+		{gol.Immigration, []byte{1, 2, 2, 0, 0, 0, 0, 0}, 2},
+		{gol.Immigration, []byte{1, 0, 1, 0, 2, 0, 0, 0}, 1},
+		{gol.QuadLife, []byte{3, 0, 3, 4, 0, 0, 0, 0}, 3},
+		{gol.QuadLife, []byte{1, 2, 3, 0, 0, 0, 0, 0}, 4},
+		{gol.QuadLife, []byte{0, 4, 0, 2, 0, 0, 3, 0}, 1},
+		{gol.QuadLife, []byte{0, 4, 0, 2, 0, 0, 0, 0}, 0},
+	}
+	for _, test := range tests {
+		if next := test.rule.Next(0, test.neighbours); next != test.expected {
+			t.Errorf("%v: cell with neighbours %v born in state %d, should be %d", test.rule, test.neighbours, next, test.expected)
+		}
+	}
+	// survivors keep their colour
+	if next := gol.QuadLife.Next(3, []byte{1, 2, 0, 0, 0, 0, 0, 0}); next != 3 {
+		t.Errorf("QuadLife: cell in state 3 with 2 neighbours goes to state %d, should stay in state 3", next)
+	}
+}
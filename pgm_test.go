@@ -6,12 +6,14 @@ import (
 	"uk.ac.bris.cs/gameoflife/gol"
 )
 
-// Pgm tests 16x16, 64x64 and 512x512 image output files on 0, 1 and 100 turns using 1-16 worker threads.
+// Pgm tests 16x16, 64x64, 512x512, 15x17 and 1000x600 image output files on 0, 1 and 100 turns using 1-16 worker threads.
 func TestPgm(t *testing.T) {
 	tests := []gol.Params{
 		{ImageWidth: 16, ImageHeight: 16},
 		{ImageWidth: 64, ImageHeight: 64},
 		{ImageWidth: 512, ImageHeight: 512},
+		{ImageWidth: 15, ImageHeight: 17},
+		{ImageWidth: 1000, ImageHeight: 600},
 	}
 	for _, p := range tests {
 		for _, turns := range []int{0, 1, 100} {
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestRules tests HighLife, Day & Night and Seeds on 16x16 and 64x64 images on 1, 10 and 100 turns using 1-16 worker threads.
+func TestRules(t *testing.T) {
+	rules := []string{"B36/S23", "B3678/S34678", "B2/S"}
+	tests := []gol.Params{
+		{ImageWidth: 16, ImageHeight: 16},
+		{ImageWidth: 64, ImageHeight: 64},
+	}
+	for _, rule := range rules {
+		for _, p := range tests {
+			p.Rule = rule
+			for _, turns := range []int{1, 10, 100} {
+				p.Turns = turns
+				expectedAlive := readAliveCells(
+					"check/rules/"+strings.Replace(rule, "/", "", 1)+fmt.Sprintf("/%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+					p.ImageWidth,
+					p.ImageHeight,
+				)
+				for threads := 1; threads <= 16; threads++ {
+					p.Threads = threads
+					testName := fmt.Sprintf("%s-%dx%dx%d-%d", rule, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+					t.Run(testName, func(t *testing.T) {
+						events := make(chan gol.Event)
+						go gol.Run(p, events, nil)
+						var cells []util.Cell
+						for event := range events {
+							switch e := event.(type) {
+							case gol.FinalTurnComplete:
+								cells = e.Alive
+							}
+						}
+						assertEqualBoard(t, cells, expectedAlive, p)
+					})
+				}
+			}
+		}
+	}
+}
+
+// TestParseRule checks that rulestrings are normalised and that invalid ones are rejected
+func TestParseRule(t *testing.T) {
+	valid := map[string]string{
+		"":             "B3/S23",
+		"B3/S23":       "B3/S23",
+		"b36/s23":      "B36/S23",
+		"S23/B36":      "B36/S23",
+		"23/36":        "B36/S23",
+		"B2/S":         "B2/S",
+		"B3678/S34678": "B3678/S34678",
+	}
+	for rulestring, expected := range valid {
+		rule, err := gol.ParseRule(rulestring)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", rulestring, err)
+		} else if rule.String() != expected {
+			t.Errorf("%q: expected %v, got %v", rulestring, expected, rule)
+		}
+	}
+	for _, rulestring := range []string{"B3", "B9/S23", "B33/S23", "B3/S2a", "X3/S23", "B3/S23/C2"} {
+		if _, err := gol.ParseRule(rulestring); err == nil {
+			t.Errorf("%q: expected an error", rulestring)
+		}
+	}
+}
@@ -4,10 +4,38 @@ import (
 	"fmt"
 	"github.com/veandco/go-sdl2/sdl"
 	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
-func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) {
-	w := NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+// Display chooses how cells are drawn in the window
+type Display int
+
+const (
+	Squares Display = iota // a pixel for each cell
+	Hexes                  // staggered hexes, for hexagonal rules such as B2/S34H
+)
+
+func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune, display Display) {
+	var w *Window
+	if display == Hexes {
+		w = NewHexWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+	} else if p.ImageDepth > 0 {
+		// a 3D world is shown a layer at a time, moved up and down with page up and page down
+		w = NewLayeredWindow(int32(p.ImageWidth), int32(p.ImageHeight), int32(p.ImageDepth))
+	} else {
+		w = NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+	}
+	// with colour, every state is drawn in its colour instead of inverting the pixels of flipped cells
+	if p.Colour {
+		palette, err := gol.StateColours(p)
+		util.Check(err)
+		w.SetPalette(palette)
+	}
+	// an unbounded world is drawn through a viewport, moved with the arrow keys and centred with 'c'
+	var view *Viewport
+	if p.Engine == gol.SparseEngine {
+		view = NewViewport(p.ImageWidth, p.ImageHeight)
+	}
 
 sdlLoop:
 	for {
@@ -24,6 +52,18 @@ sdlLoop:
 					keyPresses <- 'q'
 				case sdl.K_k:
 					keyPresses <- 'k'
+				case sdl.K_PAGEUP:
+					w.ShowLayer(w.Layer() + 1)
+					w.RenderFrame()
+				case sdl.K_PAGEDOWN:
+					w.ShowLayer(w.Layer() - 1)
+					w.RenderFrame()
+				case sdl.K_UP, sdl.K_DOWN, sdl.K_LEFT, sdl.K_RIGHT, sdl.K_c:
+					if view != nil {
+						moveViewport(view, e.Keysym.Sym)
+						view.Draw(w)
+						w.RenderFrame()
+					}
 				}
 			}
 		}
@@ -35,8 +75,27 @@ sdlLoop:
 			}
 			switch e := event.(type) {
 			case gol.CellFlipped:
-				w.FlipPixel(e.Cell.X, e.Cell.Y)
+				if view != nil {
+					view.Flip(e.Cell)
+				} else {
+					w.FlipPixel(e.Cell.X, e.Cell.Y)
+				}
+			case gol.CellsFlipped:
+				for _, cell := range e.Cells {
+					if view != nil {
+						view.Flip(cell)
+					} else {
+						w.FlipPixel(cell.X, cell.Y)
+					}
+				}
+			case gol.CellsChanged:
+				for k, cell := range e.Cells {
+					w.SetState(cell.X, cell.Y, e.States[k])
+				}
 			case gol.TurnComplete:
+				if view != nil {
+					view.Draw(w)
+				}
 				w.RenderFrame()
 			case gol.FinalTurnComplete:
 				w.Destroy()
@@ -52,3 +111,18 @@ sdlLoop:
 	}
 
 }
+
+func moveViewport(view *Viewport, key sdl.Keycode) {
+	switch key {
+	case sdl.K_UP:
+		view.Move(0, -1)
+	case sdl.K_DOWN:
+		view.Move(0, 1)
+	case sdl.K_LEFT:
+		view.Move(-1, 0)
+	case sdl.K_RIGHT:
+		view.Move(1, 0)
+	case sdl.K_c:
+		view.Centre()
+	}
+}
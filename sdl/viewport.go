@@ -0,0 +1,66 @@
+package sdl
+
+import (
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Viewport shows a window-sized area of an unbounded world, remembering every alive cell so it can be moved.
+type Viewport struct {
+	Origin        util.Cell
+	Width, Height int
+	alive         map[util.Cell]bool
+}
+
+func NewViewport(width, height int) *Viewport {
+	return &Viewport{Width: width, Height: height, alive: make(map[util.Cell]bool)}
+}
+
+func (v *Viewport) Flip(cell util.Cell) {
+	if v.alive[cell] {
+		delete(v.alive, cell)
+	} else {
+		v.alive[cell] = true
+	}
+}
+
+// Move the viewport by a quarter of its size in each direction
+func (v *Viewport) Move(dx, dy int) {
+	v.Origin.X += dx * v.Width / 4
+	v.Origin.Y += dy * v.Height / 4
+}
+
+// Centre the viewport on the bounding box of the alive cells
+func (v *Viewport) Centre() {
+	if len(v.alive) == 0 {
+		return
+	}
+	first := true
+	var min, max util.Cell
+	for cell := range v.alive {
+		if first || cell.X < min.X {
+			min.X = cell.X
+		}
+		if first || cell.Y < min.Y {
+			min.Y = cell.Y
+		}
+		if first || cell.X > max.X {
+			max.X = cell.X
+		}
+		if first || cell.Y > max.Y {
+			max.Y = cell.Y
+		}
+		first = false
+	}
+	v.Origin = util.Cell{X: (min.X+max.X)/2 - v.Width/2, Y: (min.Y+max.Y)/2 - v.Height/2}
+}
+
+// Draw the alive cells inside the viewport onto the window
+func (v *Viewport) Draw(w *Window) {
+	w.ClearPixels()
+	for cell := range v.alive {
+		x, y := cell.X-v.Origin.X, cell.Y-v.Origin.Y
+		if x >= 0 && y >= 0 && x < v.Width && y < v.Height {
+			w.SetPixel(x, y)
+		}
+	}
+}
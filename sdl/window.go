@@ -2,6 +2,7 @@ package sdl
 
 import (
 	"fmt"
+	"image/color"
 
 	"github.com/veandco/go-sdl2/sdl"
 	"uk.ac.bris.cs/gameoflife/util"
@@ -13,6 +14,12 @@ type Window struct {
 	renderer      *sdl.Renderer
 	texture       *sdl.Texture
 	pixels        []byte
+	hexagonal     bool
+	// the colour of each state and the state of each cell, when the window shows states instead of inverting pixels
+	palette []color.RGBA
+	states  []byte
+	// the layers of a 3D world are kept one after another in pixels, and only the selected one is shown
+	layers, layer int
 }
 
 func filterEvent(e sdl.Event, userdata interface{}) bool {
@@ -40,6 +47,97 @@ func NewWindow(width, height int32) *Window {
 		renderer,
 		texture,
 		make([]byte, width*height*4),
+		false,
+		nil,
+		nil,
+		1,
+		0,
+	}
+}
+
+// NewLayeredWindow creates a window for a 3D world of depth layers, which shows one layer at a time.
+// Cells are given with the layers one after another, so row y of layer z is row z*height+y.
+func NewLayeredWindow(width, height, depth int32) *Window {
+	w := NewWindow(width, height)
+	w.pixels = make([]byte, width*height*depth*4)
+	w.layers = int(depth)
+	w.ShowLayer(0)
+	return w
+}
+
+// ShowLayer selects the layer shown by the window, staying within the layers there are
+func (w *Window) ShowLayer(z int) {
+	if z < 0 {
+		z = 0
+	} else if z >= w.layers {
+		z = w.layers - 1
+	}
+	w.layer = z
+	w.window.SetTitle(fmt.Sprintf("GOL GUI - layer %d of %d", z, w.layers))
+}
+
+// Layer gets the layer shown by the window
+func (w *Window) Layer() int {
+	return w.layer
+}
+
+// NewHexWindow creates a window that draws the cells of a hexagonal grid as staggered hexes.
+// Each cell is 2 pixels wide and each row is shifted half a cell left of the row above, wrapping around the sides,
+// so every cell touches its 6 neighbours.
+func NewHexWindow(width, height int32) *Window {
+	w := NewWindow(2*width, height)
+	w.hexagonal = true
+	return w
+}
+
+// get the pixels in the row that show the cell in column x
+func (w *Window) cellPixels(x, y int) []int {
+	if !w.hexagonal {
+		return []int{x}
+	}
+	width := int(w.Width)
+	left := ((2*x-y)%width + width) % width
+	return []int{left, (left + 1) % width}
+}
+
+// get the number of rows of cells in every layer
+func (w *Window) rows() int {
+	return int(w.Height) * w.layers
+}
+
+// get the number of cells in each row
+func (w *Window) columns() int {
+	if w.hexagonal {
+		return int(w.Width) / 2
+	}
+	return int(w.Width)
+}
+
+// SetPalette makes the window draw each cell in the colour of its state, such as the palette from gol.StateColours.
+// Every cell starts in state 0 and flipped cells switch between states 0 and 1.
+func (w *Window) SetPalette(palette []color.RGBA) {
+	w.palette = palette
+	w.states = make([]byte, w.columns()*w.rows())
+	w.ClearPixels()
+}
+
+// SetState draws a cell in the colour of its new state. The window must have a palette
+func (w *Window) SetState(x, y int, state byte) {
+	if x < 0 || y < 0 || x >= w.columns() || y >= w.rows() {
+		panic(fmt.Sprintf("CellsChanged event at (%d, %d) is outside the bounds of the window.", x, y))
+	}
+	w.states[y*w.columns()+x] = state
+	w.paint(x, y, w.palette[state])
+}
+
+// set the pixels of a cell to a colour, stored as BGRA
+func (w *Window) paint(x, y int, colour color.RGBA) {
+	width := int(w.Width)
+	for _, x := range w.cellPixels(x, y) {
+		w.pixels[4*(y*width+x)+0] = colour.B
+		w.pixels[4*(y*width+x)+1] = colour.G
+		w.pixels[4*(y*width+x)+2] = colour.R
+		w.pixels[4*(y*width+x)+3] = colour.A
 	}
 }
 
@@ -54,7 +152,8 @@ func (w *Window) Destroy() {
 }
 
 func (w *Window) RenderFrame() {
-	err := w.texture.Update(nil, w.pixels, int(w.Width*4))
+	size := int(w.Width) * int(w.Height) * 4
+	err := w.texture.Update(nil, w.pixels[w.layer*size:(w.layer+1)*size], int(w.Width*4))
 	util.Check(err)
 	err = w.renderer.Clear()
 	util.Check(err)
@@ -68,28 +167,45 @@ func (w *Window) PollEvent() sdl.Event {
 }
 
 func (w *Window) SetPixel(x, y int) {
+	if w.palette != nil {
+		w.SetState(x, y, 1)
+		return
+	}
 	width := int(w.Width)
-	w.pixels[4*(y*width+x)+0] = 0xFF
-	w.pixels[4*(y*width+x)+1] = 0xFF
-	w.pixels[4*(y*width+x)+2] = 0xFF
-	w.pixels[4*(y*width+x)+3] = 0xFF
+	for _, x := range w.cellPixels(x, y) {
+		w.pixels[4*(y*width+x)+0] = 0xFF
+		w.pixels[4*(y*width+x)+1] = 0xFF
+		w.pixels[4*(y*width+x)+2] = 0xFF
+		w.pixels[4*(y*width+x)+3] = 0xFF
+	}
 }
 
 func (w *Window) FlipPixel(x, y int) {
-	if x < 0 || y < 0 || x >= int(w.Width) || y >= int(w.Height) {
+	if x < 0 || y < 0 || x >= w.columns() || y >= w.rows() {
 		panic(fmt.Sprintf("CellFlipped event at (%d, %d) is outside the bounds of the window.", x, y))
 	}
+	if w.palette != nil {
+		// an alive cell dies, anything else becomes alive
+		if w.states[y*w.columns()+x] == 1 {
+			w.SetState(x, y, 0)
+		} else {
+			w.SetState(x, y, 1)
+		}
+		return
+	}
 
 	width := int(w.Width)
-	w.pixels[4*(y*width+x)+0] = ^w.pixels[4*(y*width+x)+0]
-	w.pixels[4*(y*width+x)+1] = ^w.pixels[4*(y*width+x)+1]
-	w.pixels[4*(y*width+x)+2] = ^w.pixels[4*(y*width+x)+2]
-	w.pixels[4*(y*width+x)+3] = ^w.pixels[4*(y*width+x)+3]
+	for _, x := range w.cellPixels(x, y) {
+		w.pixels[4*(y*width+x)+0] = ^w.pixels[4*(y*width+x)+0]
+		w.pixels[4*(y*width+x)+1] = ^w.pixels[4*(y*width+x)+1]
+		w.pixels[4*(y*width+x)+2] = ^w.pixels[4*(y*width+x)+2]
+		w.pixels[4*(y*width+x)+3] = ^w.pixels[4*(y*width+x)+3]
+	}
 }
 
 func (w *Window) CountPixels() int {
 	count := 0
-	for i := 0; i < int(w.Width) * int(w.Height) * 4; i += 4 {
+	for i := 0; i < int(w.Width) * w.rows() * 4; i += 4 {
 		if w.pixels[i] == 0xFF {
 			count++
 		}
@@ -101,4 +217,11 @@ func (w *Window) ClearPixels() {
 	for i := range w.pixels {
 		w.pixels[i] = 0
 	}
+	if w.palette != nil {
+		for y := 0; y < w.rows(); y++ {
+			for x := 0; x < w.columns(); x++ {
+				w.SetState(x, y, 0)
+			}
+		}
+	}
 }
@@ -59,6 +59,13 @@ sdlLoop:
 				if w != nil {
 					w.FlipPixel(e.Cell.X, e.Cell.Y)
 				}
+			case gol.CellsFlipped:
+				for _, cell := range e.Cells {
+					board[cell.Y][cell.X] = ^board[cell.Y][cell.X]
+					if w != nil {
+						w.FlipPixel(cell.X, cell.Y)
+					}
+				}
 			case gol.TurnComplete:
 				if w != nil {
 					w.RenderFrame()
@@ -89,40 +96,53 @@ sdlLoop:
 	os.Exit(<-result)
 }
 
-// TestSdl tests a 512x512 image for 100 turns using 8 worker threads.
+// TestSdl tests a 512x512 image for 100 turns using 8 worker threads,
+// first with a CellFlipped event per cell and then with batched CellsFlipped events.
 func TestSdl(t *testing.T) {
-	p := gol.Params{ImageWidth: 512, ImageHeight: 512, Turns: 100, Threads: 8}
-	testName := fmt.Sprintf("%dx%dx%d-%d", p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
-	alive := readAliveCounts(p.ImageWidth, p.ImageHeight)
-	t.Run(testName, func(t *testing.T) {
-		turnNum := 0
-		events := make(chan gol.Event)
-		go gol.Run(p, events, nil)
-		time.Sleep(2 * time.Second)
-		final := false
-		for event := range events {
-			switch e := event.(type) {
-			case gol.CellFlipped:
-				sdlEvents <- e
-			case gol.TurnComplete:
-				turnNum++
-				sdlEvents <- e
-				aliveCount := <-sdlAlive
-				if alive[turnNum] != aliveCount {
-					t.Logf("Incorrect number of alive cells displayed on turn %d. Was %d, should be %d.", turnNum, aliveCount, alive[turnNum])
-					time.Sleep(5 * time.Second)
-					sdlEvents <- gol.FinalTurnComplete{}
-					t.FailNow()
+	for _, batch := range []bool{false, true} {
+		p := gol.Params{ImageWidth: 512, ImageHeight: 512, Turns: 100, Threads: 8, BatchFlips: batch}
+		testName := fmt.Sprintf("%dx%dx%d-%d-batch=%v", p.ImageWidth, p.ImageHeight, p.Turns, p.Threads, p.BatchFlips)
+		alive := readAliveCounts(p.ImageWidth, p.ImageHeight)
+		last := batch
+		// a failed run closes the window, so there is nothing left to test
+		ok := t.Run(testName, func(t *testing.T) {
+			turnNum := 0
+			events := make(chan gol.Event)
+			go gol.Run(p, events, nil)
+			time.Sleep(2 * time.Second)
+			final := false
+			for event := range events {
+				switch e := event.(type) {
+				case gol.CellFlipped, gol.CellsFlipped:
+					sdlEvents <- e
+				case gol.TurnComplete:
+					turnNum++
+					sdlEvents <- e
+					aliveCount := <-sdlAlive
+					if alive[turnNum] != aliveCount {
+						t.Logf("Incorrect number of alive cells displayed on turn %d. Was %d, should be %d.", turnNum, aliveCount, alive[turnNum])
+						time.Sleep(5 * time.Second)
+						sdlEvents <- gol.FinalTurnComplete{}
+						t.FailNow()
+					}
+				case gol.FinalTurnComplete:
+					final = true
+					if last {
+						sdlEvents <- e
+					} else {
+						// clear the board for the next run, as the window closes on FinalTurnComplete
+						sdlEvents <- gol.CellsFlipped{CompletedTurns: e.CompletedTurns, Cells: e.Alive}
+					}
 				}
-			case gol.FinalTurnComplete:
-				final = true
-				sdlEvents <- e
 			}
-		}
 
-		if !final {
-			sdlEvents <- gol.FinalTurnComplete{}
-			t.Fatal("Simulation finished without sending a FinalTurnComplete event.")
+			if !final {
+				sdlEvents <- gol.FinalTurnComplete{}
+				t.Fatal("Simulation finished without sending a FinalTurnComplete event.")
+			}
+		})
+		if !ok {
+			return
 		}
-	})
+	}
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestStochastic tests Life where births happen 95% of the time and 1% of surviving cells die anyway,
+// for 1, 10 and 100 turns using 1-16 worker threads. Every split of the world must give the images in check/stochastic.
+func TestStochastic(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Stochastic: true, BirthProbability: 0.95, DeathProbability: 0.01, Seed: 42}
+	for _, turns := range []int{1, 10, 100} {
+		p.Turns = turns
+		path := fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns)
+		expected, err := ioutil.ReadFile("check/stochastic/" + path)
+		util.Check(err)
+		expectedAlive := readAliveCells("check/stochastic/"+path, p.ImageWidth, p.ImageHeight)
+		for threads := 1; threads <= 16; threads++ {
+			p.Threads = threads
+			testName := fmt.Sprintf("%dx%dx%d-%d", p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+			t.Run(testName, func(t *testing.T) {
+				assertEqualBoard(t, runFinal(p), expectedAlive, p)
+				output, err := ioutil.ReadFile("out/" + path)
+				util.Check(err)
+				if !bytes.Equal(output, expected) {
+					t.Errorf("out/%v does not match check/stochastic/%v", path, path)
+				}
+			})
+		}
+	}
+}
+
+// TestStochasticSeeds tests that a stochastic Generations rule gives the same states with any number of threads,
+// that another seed gives different states, and that certain births and no deaths give the rule itself.
+func TestStochasticSeeds(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 10, Rule: "B2/S345/C4",
+		Stochastic: true, BirthProbability: 0.7, DeathProbability: 0.1, Seed: 7}
+	run := func(p gol.Params) [][]byte {
+		events := make(chan gol.Event)
+		go gol.Run(p, events, nil)
+		var states [][]byte
+		for event := range events {
+			if e, ok := event.(gol.FinalTurnComplete); ok {
+				states = e.States
+			}
+		}
+		return states
+	}
+	p.Threads = 1
+	expected := run(p)
+	for _, threads := range []int{2, 3, 5, 8, 16} {
+		p.Threads = threads
+		states := run(p)
+		for y, row := range expected {
+			if !bytes.Equal(states[y], row) {
+				t.Fatalf("%d threads give row %d states %v, should be %v", threads, y, states[y], row)
+			}
+		}
+	}
+
+	p.Seed = 8
+	if states := run(p); fmt.Sprint(states) == fmt.Sprint(expected) {
+		t.Errorf("seeds 7 and 8 give the same states")
+	}
+
+	p = gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 8, Stochastic: true, BirthProbability: 1, Seed: 7}
+	assertEqualBoard(t, runFinal(p), readAliveCells("check/images/64x64x100.pgm", p.ImageWidth, p.ImageHeight), p)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// margin of dead cells around the images in check/unbounded, wide enough that nothing reaches their edges in 100 turns
+const unboundedMargin = 128
+
+// TestUnbounded tests 16x16, 64x64 and 64x48 images on an unbounded plane for 100 turns using 1-16 worker threads.
+// The gliders in 64x48 fly off every side, so cells end up with negative coordinates. The output PGM must hold
+// the bounding box of the alive cells and the original image.
+func TestUnbounded(t *testing.T) {
+	tests := []gol.Params{
+		{ImageWidth: 16, ImageHeight: 16},
+		{ImageWidth: 64, ImageHeight: 64},
+		{ImageWidth: 64, ImageHeight: 48},
+	}
+	for _, p := range tests {
+		p.Turns = 100
+		p.Engine = gol.SparseEngine
+		var expectedAlive []util.Cell
+		origin := util.Cell{}
+		end := util.Cell{X: p.ImageWidth, Y: p.ImageHeight}
+		for _, cell := range readAliveCells(
+			"check/unbounded/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+			p.ImageWidth+2*unboundedMargin,
+			p.ImageHeight+2*unboundedMargin,
+		) {
+			cell = util.Cell{X: cell.X - unboundedMargin, Y: cell.Y - unboundedMargin}
+			expectedAlive = append(expectedAlive, cell)
+			origin = util.Cell{X: minInt(origin.X, cell.X), Y: minInt(origin.Y, cell.Y)}
+			end = util.Cell{X: maxInt(end.X, cell.X+1), Y: maxInt(end.Y, cell.Y+1)}
+		}
+		for threads := 1; threads <= 16; threads++ {
+			p.Threads = threads
+			testName := fmt.Sprintf("%dx%dx%d-%d", p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+			t.Run(testName, func(t *testing.T) {
+				assertEqualBoard(t, runFinal(p), expectedAlive, p)
+
+				var cellsFromImage []util.Cell
+				for _, cell := range readAliveCells(
+					"out/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+					end.X-origin.X,
+					end.Y-origin.Y,
+				) {
+					cellsFromImage = append(cellsFromImage, util.Cell{X: cell.X + origin.X, Y: cell.Y + origin.Y})
+				}
+				assertEqualBoard(t, cellsFromImage, expectedAlive, p)
+			})
+		}
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestBays tests Bays' 3D rules 4555 and 5766 on a 24x24x12 world, read from the slices in images/24x24x12,
+// with the edges wrapped around and with dead edges, using 1-16 worker threads.
+// Every output slice must match check/bays.
+func TestBays(t *testing.T) {
+	tests := []struct {
+		rule     string
+		boundary gol.Boundary
+		check    string
+	}{
+		{"4555", gol.Torus, "check/bays/4555"},
+		{"5766", gol.Torus, "check/bays/5766"},
+		{"4555", gol.DeadEdges, "check/bays/4555-dead"},
+	}
+	for _, test := range tests {
+		for _, turns := range []int{1, 10, 30} {
+			p := gol.Params{ImageWidth: 24, ImageHeight: 24, ImageDepth: 12, Turns: turns, Rule: test.rule, Boundary: test.boundary}
+			dir := fmt.Sprintf("%vx%vx%vx%v", p.ImageWidth, p.ImageHeight, p.ImageDepth, p.Turns)
+			var expectedAlive []util.Cell
+			for z := 0; z < p.ImageDepth; z++ {
+				for _, cell := range readAliveCells(fmt.Sprintf("%v/%v/%d.pgm", test.check, dir, z), p.ImageWidth, p.ImageHeight) {
+					expectedAlive = append(expectedAlive, util.Cell{X: cell.X, Y: z*p.ImageHeight + cell.Y})
+				}
+			}
+			for _, threads := range []int{1, 2, 3, 8, 12, 16} {
+				p.Threads = threads
+				testName := fmt.Sprintf("%v-%v-%v-%d", test.rule, test.boundary, dir, p.Threads)
+				t.Run(testName, func(t *testing.T) {
+					assertEqualBoard(t, runFinal(p), expectedAlive, stacked(p))
+					for z := 0; z < p.ImageDepth; z++ {
+						path := fmt.Sprintf("%v/%d.pgm", dir, z)
+						expected, err := ioutil.ReadFile(test.check + "/" + path)
+						util.Check(err)
+						output, err := ioutil.ReadFile("out/" + path)
+						util.Check(err)
+						if !bytes.Equal(output, expected) {
+							t.Errorf("out/%v does not match %v/%v", path, test.check, path)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestBaysGlider tests the 5766 glider, two layers of the glider from Conway's Life, moving diagonally at c/4.
+// It is flown in the x-y plane of a 16x16x8 world, the x-z plane of a 16x8x16 world and the y-z plane
+// of a 8x16x16 world, so the layers above and below count as neighbours the same as the cells around in a layer.
+func TestBaysGlider(t *testing.T) {
+	glider := []util.Cell{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 0}, {X: 2, Y: 1}}
+	// put a cell of the plane, and its layer of the glider, in 3D
+	planes := []struct {
+		size  [3]int
+		place func(a, b, c int) [3]int
+	}{
+		{[3]int{16, 16, 8}, func(a, b, c int) [3]int { return [3]int{a, b, c} }},
+		{[3]int{16, 8, 16}, func(a, b, c int) [3]int { return [3]int{a, c, b} }},
+		{[3]int{8, 16, 16}, func(a, b, c int) [3]int { return [3]int{c, a, b} }},
+	}
+	for _, plane := range planes {
+		for _, turns := range []int{0, 4, 20, 64} {
+			p := gol.Params{ImageWidth: plane.size[0], ImageHeight: plane.size[1], ImageDepth: plane.size[2], Turns: turns, Rule: "5766"}
+			// it starts at (6, 6) and moves one cell up and left of the plane every 4 turns
+			var expectedAlive []util.Cell
+			for _, cell := range glider {
+				for c := 3; c <= 4; c++ {
+					position := plane.place((cell.X+6-turns/4+16)%16, (cell.Y+6-turns/4+16)%16, c)
+					expectedAlive = append(expectedAlive, util.Cell{X: position[0], Y: position[2]*p.ImageHeight + position[1]})
+				}
+			}
+			for _, threads := range []int{1, 3, 8, 16} {
+				p.Threads = threads
+				testName := fmt.Sprintf("%vx%vx%vx%v-%d", p.ImageWidth, p.ImageHeight, p.ImageDepth, p.Turns, p.Threads)
+				t.Run(testName, func(t *testing.T) {
+					assertEqualBoard(t, runFinal(p), expectedAlive, stacked(p))
+				})
+			}
+		}
+	}
+}
+
+// TestParseBays tests parsing rulestrings in Bays' notation.
+func TestParseBays(t *testing.T) {
+	valid := map[string]gol.BaysRule{
+		"4555":        {SurvivalMin: 4, SurvivalMax: 5, BirthMin: 5, BirthMax: 5},
+		"5766":        {SurvivalMin: 5, SurvivalMax: 7, BirthMin: 6, BirthMax: 6},
+		"10,21,12,12": {SurvivalMin: 10, SurvivalMax: 21, BirthMin: 12, BirthMax: 12},
+		" 4,5,5,5":    {SurvivalMin: 4, SurvivalMax: 5, BirthMin: 5, BirthMax: 5},
+	}
+	for rulestring, expected := range valid {
+		rule, err := gol.ParseRulestring(rulestring)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", rulestring, err)
+		} else if rule != expected {
+			t.Errorf("%q parsed as %v, should be %v", rulestring, rule, expected)
+		}
+	}
+	for _, rule := range []gol.BaysRule{valid["4555"], valid["10,21,12,12"]} {
+		if parsed, err := gol.ParseBays(rule.String()); err != nil || parsed != rule {
+			t.Errorf("%v does not parse back from its String, got %v, %v", rule, parsed, err)
+		}
+	}
+	for _, rulestring := range []string{"455", "45555", "5455", "4,5,5", "4,5,27,27", "4,5,a,5", ","} {
+		if _, err := gol.ParseBays(rulestring); err == nil {
+			t.Errorf("%q: expected an error", rulestring)
+		}
+	}
+}
+
+// get the params of the 2D board holding the layers of a 3D world one after another
+func stacked(p gol.Params) gol.Params {
+	p.ImageHeight *= p.ImageDepth
+	p.ImageDepth = 0
+	return p
+}
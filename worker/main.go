@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// main starts a worker with 'go run ./worker', which registers with a broker and computes the strips it is given
+func main() {
+	address := flag.String(
+		"addr",
+		"127.0.0.1:8031",
+		"Specify the address to listen on for the broker, which must be able to reach it. Defaults to 127.0.0.1:8031.")
+
+	broker := flag.String(
+		"broker",
+		"127.0.0.1:8030",
+		"Specify the address of the broker to register with. Defaults to 127.0.0.1:8030.")
+
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", *address)
+	util.Check(err)
+	closing := &closingListener{Listener: listener, closed: make(chan bool)}
+	util.Check(gol.ServeWorker(closing, *broker))
+	// the first line shows the worker has registered with the broker
+	fmt.Println("Worker:", listener.Addr())
+	// the worker closes its listener when the broker shuts it down
+	<-closing.closed
+}
+
+// closingListener tells main when it is closed
+type closingListener struct {
+	net.Listener
+	once   sync.Once
+	closed chan bool
+}
+
+func (l *closingListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}